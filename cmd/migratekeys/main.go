@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+
+	"silvatek.uk/trustedassertions/internal/appcontext"
+	"silvatek.uk/trustedassertions/internal/datastore"
+	"silvatek.uk/trustedassertions/internal/keyvault"
+	"silvatek.uk/trustedassertions/internal/logging"
+)
+
+var log = logging.GetLogger("migratekeys")
+
+// Wraps every entity private key in the Firestore database with the current master key, including
+// keys stored before the key vault was introduced and keys wrapped with a previous master key.
+//
+// The master key is taken from KEY_VAULT_MASTER_KEY, and any previous master keys from the
+// comma-separated KEY_VAULT_PREVIOUS_KEYS, as they are for the server.
+func main() {
+	ctx := appcontext.InitContext()
+	logging.StructureLogs = (os.Getenv("GCLOUD_PROJECT") != "")
+	log.Info("TrustedAssertions key migration")
+
+	if os.Getenv("FIRESTORE_DB_NAME") == "" {
+		log.Errorf("FIRESTORE_DB_NAME must be set to the database whose keys are to be migrated")
+		os.Exit(1)
+	}
+	if os.Getenv("KEY_VAULT_MASTER_KEY") == "" {
+		log.Errorf("KEY_VAULT_MASTER_KEY must be set to the master key to wrap keys with, 32 random bytes encoded as base64")
+		os.Exit(1)
+	}
+
+	vault, err := keyvault.FromConfig(os.Getenv("KEY_VAULT_MASTER_KEY"), os.Getenv("KEY_VAULT_PREVIOUS_KEYS"))
+	if err != nil {
+		log.Errorf("Invalid key vault configuration: %v", err)
+		os.Exit(1)
+	}
+	keyvault.ActiveKeyVault = vault
+	datastore.InitFireStore(ctx)
+
+	migrated, skipped, err := datastore.MigrateKeys(ctx)
+	if err != nil {
+		log.Errorf("Key migration stopped after %d keys: %v", migrated, err)
+		os.Exit(1)
+	}
+	log.Infof("Migrated %d keys", migrated)
+	if len(skipped) > 0 {
+		log.Errorf("Skipped %d keys that could not be unwrapped: %v", len(skipped), skipped)
+		os.Exit(1)
+	}
+}
@@ -10,9 +10,11 @@ import (
 	"silvatek.uk/trustedassertions/internal/appcontext"
 	"silvatek.uk/trustedassertions/internal/assertions"
 	"silvatek.uk/trustedassertions/internal/datastore"
+	"silvatek.uk/trustedassertions/internal/keyvault"
 	"silvatek.uk/trustedassertions/internal/logging"
 	. "silvatek.uk/trustedassertions/internal/references"
 	"silvatek.uk/trustedassertions/internal/testdata"
+	"silvatek.uk/trustedassertions/internal/trust"
 	"silvatek.uk/trustedassertions/internal/web"
 
 	"github.com/gorilla/csrf"
@@ -47,7 +49,7 @@ func main() {
 	handlers.CompressHandler(r)
 
 	srv := &http.Server{
-		Handler:      CSRF(r),
+		Handler:      exemptSubmissionsFromCsrf(CSRF(r)),
 		Addr:         listenAddress(),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 5 * time.Second,
@@ -56,6 +58,18 @@ func main() {
 	srv.ListenAndServe()
 }
 
+// Submitted assertions carry their own signatures and do not rely on the session cookie, so submitting
+// them through the API is exempt from the CSRF checks. Every other request that changes anything,
+// including those to the rest of the API, is checked in the same way as the web forms.
+func exemptSubmissionsFromCsrf(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/api/v1/assertions" {
+			r = csrf.UnsafeSkipCheck(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func getEnvWithDefault(env_var string, defaultValue string) string {
 	value, found := os.LookupEnv(env_var)
 	if !found {
@@ -84,9 +98,55 @@ func initDataStore(ctx context.Context) {
 		datastore.InitInMemoryDataStore()
 	}
 
+	vault, err := keyvault.FromConfig(os.Getenv("KEY_VAULT_MASTER_KEY"), os.Getenv("KEY_VAULT_PREVIOUS_KEYS"))
+	if err != nil {
+		log.ErrorfX(ctx, "Invalid key vault configuration: %v", err)
+		os.Exit(1)
+	}
+	keyvault.ActiveKeyVault = vault
+	// Keys are only stored unencrypted in a persistent datastore if that has been explicitly allowed for development
+	if _, plaintext := vault.(keyvault.PlaintextVault); plaintext && !datastore.ActiveDataStore.AutoInit() {
+		if os.Getenv("KEY_VAULT_ALLOW_PLAINTEXT") != "true" {
+			log.ErrorfX(ctx, "KEY_VAULT_MASTER_KEY must be set, unless KEY_VAULT_ALLOW_PLAINTEXT is true for a development datastore")
+			os.Exit(1)
+		}
+		log.ErrorfX(ctx, "KEY_VAULT_MASTER_KEY not set, so entity private keys will be stored unencrypted")
+	}
+
 	if defaultEntityUri == "" {
 		defaultEntityUri = os.Getenv("DEFAULT_ENTITY")
 		web.DefaultEntityUri = UriFromString(defaultEntityUri)
+		trust.DefaultRoots = trust.NewRoots(web.DefaultEntityUri)
+	}
+
+	timestampAuthority := os.Getenv("TIMESTAMP_AUTHORITY")
+	if timestampAuthority == "" {
+		timestampAuthority = defaultEntityUri
+	}
+	if timestampAuthority != "" {
+		assertions.TimestampAuthority = UriFromString(timestampAuthority)
+	}
+
+	if halfLives := os.Getenv("TRUST_HALF_LIVES"); halfLives != "" {
+		if parsed, err := trust.ParseHalfLives(halfLives); err != nil {
+			log.ErrorfX(ctx, "Ignoring TRUST_HALF_LIVES: %v", err)
+		} else {
+			trust.HalfLives = parsed
+		}
+	}
+
+	if modelName := os.Getenv("TRUST_MODEL"); modelName != "" {
+		if _, err := trust.NewModel(modelName, datastore.ActiveDataStore); err != nil {
+			log.ErrorfX(ctx, "Ignoring TRUST_MODEL: %v", err)
+		} else {
+			trust.DefaultModel = modelName
+		}
+	}
+
+	if categoriesFile := os.Getenv("CATEGORIES_FILE"); categoriesFile != "" {
+		if err := assertions.LoadCategories(categoriesFile); err != nil {
+			log.ErrorfX(ctx, "Ignoring CATEGORIES_FILE: %v", err)
+		}
 	}
 
 	if defaultEntityKey == "" {
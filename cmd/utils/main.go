@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/rsa"
 	"math/big"
 	"os"
 
@@ -16,7 +17,11 @@ func main() {
 	log.Info("TrustedAssertions utils")
 
 	b64key := os.Getenv("PRV_KEY")
-	prvKey := entities.PrivateKeyFromString(b64key)
+	prvKey, ok := entities.PrivateKeyFromString(b64key).(*rsa.PrivateKey)
+	if !ok {
+		log.Errorf("Private key is not an RSA key")
+		return
+	}
 	err := prvKey.Validate()
 	if err != nil {
 		log.Errorf("Private key is not valid: %v", err)
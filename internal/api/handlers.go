@@ -1,19 +1,28 @@
 package api
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"silvatek.uk/trustedassertions/internal/appcontext"
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/auth"
 	"silvatek.uk/trustedassertions/internal/datastore"
 	log "silvatek.uk/trustedassertions/internal/logging"
 	"silvatek.uk/trustedassertions/internal/references"
+	"silvatek.uk/trustedassertions/internal/trust"
 )
 
 func AddHandlers(r *mux.Router) {
 	r.HandleFunc("/api/v1/statements/{key}", StatementApiHandler)
+	r.HandleFunc("/api/v1/statements/{key}/trust", StatementTrustApiHandler)
 	r.HandleFunc("/api/v1/entities/{key}", EntityApiHandler)
+	r.HandleFunc("/api/v1/assertions", SubmitAssertionApiHandler)
 	r.HandleFunc("/api/v1/assertions/{key}", AssertionApiHandler)
+	r.HandleFunc("/api/v1/coseassertions/{key}", AssertionApiHandler)
 
 	//r.HandleFunc("/api/v1/reindex", ReindexApiHandler)
 }
@@ -32,6 +41,49 @@ func StatementApiHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(statement.Content()))
 }
 
+// Returns the trust score for a statement, with the tree of assertions and entities behind it, as JSON.
+//
+// The trust model can be chosen with the "model" query parameter, otherwise the server default is used.
+func StatementTrustApiHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := appcontext.NewWebContext(r)
+	uri := references.MakeUri(mux.Vars(r)["key"], "statement")
+
+	if _, err := datastore.ActiveDataStore.FetchStatement(ctx, uri); err != nil {
+		setHeaders(w, http.StatusNotFound, "text/plain")
+		w.Write([]byte("Statement not found: " + uri.String()))
+		return
+	}
+
+	modelName := r.URL.Query().Get("model")
+	if modelName == "" {
+		modelName = trust.DefaultModel
+	}
+	model, err := trust.NewModel(modelName, datastore.ActiveDataStore)
+	if err != nil {
+		setHeaders(w, http.StatusBadRequest, "text/plain")
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	score, err := model.Score(ctx, uri, trust.RootsForUser(ctx, datastore.ActiveDataStore, auth.RequestUsername(r)))
+	if err != nil {
+		setHeaders(w, http.StatusInternalServerError, "text/plain")
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	data := struct {
+		Score       trust.Score        `json:"score"`
+		Explanation *trust.Explanation `json:"explanation"`
+	}{
+		Score:       score,
+		Explanation: trust.Explain(ctx, datastore.ActiveDataStore, score),
+	}
+
+	setHeaders(w, http.StatusOK, "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
 func EntityApiHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := appcontext.NewWebContext(r)
 	key := mux.Vars(r)["key"]
@@ -52,10 +104,63 @@ func AssertionApiHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Clients that ask for COSE get the binary structure rather than its text encoding
+	if assertion.Format() == assertions.CoseFormat && strings.Contains(r.Header.Get("Accept"), COSE_CONTENT_TYPE) {
+		raw, _ := assertions.DecodeCose(assertion.Content())
+		setHeaders(w, http.StatusOK, COSE_CONTENT_TYPE)
+		w.Write(raw)
+		return
+	}
+
 	setHeaders(w, http.StatusOK, "text/plain")
 	w.Write([]byte(assertion.Content()))
 }
 
+// The media type of binary COSE structures.
+const COSE_CONTENT_TYPE = "application/cose"
+
+// The largest pre-signed assertion that can be submitted, in bytes.
+const maxSubmissionSize = 64 * 1024
+
+// Stores an assertion that has already been signed with the issuer's own key, so that the key need never
+// be held by the server. The body of the POST request is the compact JWT or encoded COSE_Sign1 structure of
+// the assertion, or the binary COSE_Sign1 structure if the content type is application/cose. The response
+// is the URI of the stored assertion, or the reason it was rejected.
+func SubmitAssertionApiHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := appcontext.NewWebContext(r)
+
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		setHeaders(w, http.StatusMethodNotAllowed, "text/plain")
+		w.Write([]byte("Assertions must be submitted with POST"))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSubmissionSize))
+	if err != nil {
+		setHeaders(w, http.StatusRequestEntityTooLarge, "text/plain")
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	content := string(body)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), COSE_CONTENT_TYPE) {
+		content = assertions.EncodeCose(body)
+	}
+
+	assertion, err := datastore.StoreSignedAssertion(ctx, content)
+	if err != nil {
+		log.InfofX(ctx, "Rejected submitted assertion: %v", err)
+		setHeaders(w, http.StatusBadRequest, "text/plain")
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Location", assertion.Uri().ApiPath())
+	setHeaders(w, http.StatusCreated, "text/plain")
+	w.Write([]byte(assertion.Uri().String()))
+}
+
 func ReindexApiHandler(w http.ResponseWriter, r *http.Request) {
 	setHeaders(w, http.StatusOK, "text/plain")
 	w.Write([]byte("Reindexing..."))
@@ -66,7 +171,7 @@ func ReindexApiHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func setHeaders(w http.ResponseWriter, httpStatus int, contentType string) {
-	w.WriteHeader(httpStatus)
 	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("X-Robots-Tag", "noindex")
+	w.WriteHeader(httpStatus)
 }
@@ -1,19 +1,27 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"crypto/rand"
 	"crypto/rsa"
 	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/auth"
 	"silvatek.uk/trustedassertions/internal/datastore"
 	"silvatek.uk/trustedassertions/internal/entities"
+	"silvatek.uk/trustedassertions/internal/references"
 	"silvatek.uk/trustedassertions/internal/statements"
+	"silvatek.uk/trustedassertions/internal/trust"
 )
 
 func TestStatmentApi(t *testing.T) {
@@ -90,3 +98,238 @@ func TestAssertionApi(t *testing.T) {
 		t.Errorf("Unexpected response body: %s", response)
 	}
 }
+
+func TestSubmitAssertionApi(t *testing.T) {
+	router := mux.NewRouter()
+	AddHandlers(router)
+
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	entity := entities.NewEntity("Offline signer", *big.NewInt(1234))
+	entity.MakeCertificate(privateKey)
+
+	statement := statements.NewStatement("test")
+
+	datastore.InitInMemoryDataStore()
+	assertions.PublicKeyResolver = datastore.ActiveDataStore
+
+	datastore.ActiveDataStore.Store(context.TODO(), &entity)
+	datastore.ActiveDataStore.Store(context.TODO(), statement)
+
+	assertion := assertions.NewAssertion("IsTrue")
+	assertion.Issuer = entity.Uri().String()
+	assertion.Subject = statement.Uri().String()
+	assertion.IssuedAt = jwt.NewNumericDate(time.Now())
+	assertion.MakeJwt(privateKey)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/api/v1/assertions", strings.NewReader(assertion.Content()))
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusCreated || w.Body.String() != assertion.Uri().String() {
+		t.Errorf("Unexpected response: %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Location") != assertion.Uri().ApiPath() {
+		t.Errorf("Unexpected location: %s", w.Header().Get("Location"))
+	}
+	if _, err := datastore.ActiveDataStore.FetchAssertion(context.TODO(), assertion.Uri()); err != nil {
+		t.Errorf("Submitted assertion not stored: %v", err)
+	}
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	forged := assertions.NewAssertion("IsFalse")
+	forged.Issuer = entity.Uri().String()
+	forged.Subject = statement.Uri().String()
+	forged.IssuedAt = jwt.NewNumericDate(time.Now())
+	forged.MakeJwt(otherKey)
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("POST", "/api/v1/assertions", strings.NewReader(forged.Content()))
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Unexpected status for a forged assertion: %d", w.Code)
+	}
+
+	// Categories must be registered and allow the kind of subject
+	for _, category := range []string{"IsBogus", "IsTrusted"} {
+		invalid := assertions.NewAssertion(assertions.AssertionType(category))
+		invalid.Issuer = entity.Uri().String()
+		invalid.Subject = statement.Uri().String()
+		invalid.IssuedAt = jwt.NewNumericDate(time.Now())
+		invalid.MakeJwt(privateKey)
+
+		w = httptest.NewRecorder()
+		r, _ = http.NewRequest("POST", "/api/v1/assertions", strings.NewReader(invalid.Content()))
+
+		router.ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), datastore.ErrInvalidCategory.Error()) {
+			t.Errorf("Unexpected response for %s about a statement: %d %s", category, w.Code, w.Body.String())
+		}
+		if _, err := datastore.ActiveDataStore.FetchAssertion(context.TODO(), invalid.Uri()); err == nil {
+			t.Errorf("Assertion with an invalid %s category should not be stored", category)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "/api/v1/assertions", nil)
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Unexpected status for GET: %d", w.Code)
+	}
+}
+
+func TestCoseAssertionApi(t *testing.T) {
+	router := mux.NewRouter()
+	AddHandlers(router)
+
+	privateKey, _ := entities.GenerateKey(entities.Ed25519)
+	entity := entities.Entity{CommonName: "Compact signer", Issued: time.Now().Add(-time.Hour)}
+	entity.MakeCertificate(privateKey)
+
+	statement := statements.NewStatement("test")
+
+	datastore.InitInMemoryDataStore()
+	assertions.PublicKeyResolver = datastore.ActiveDataStore
+
+	datastore.ActiveDataStore.Store(context.TODO(), &entity)
+	datastore.ActiveDataStore.Store(context.TODO(), statement)
+
+	assertion := assertions.NewAssertion("IsTrue")
+	assertion.Issuer = entity.Uri().String()
+	assertion.Subject = statement.Uri().String()
+	assertion.IssuedAt = jwt.NewNumericDate(time.Now())
+	assertion.MakeCose(privateKey)
+	raw, _ := assertions.DecodeCose(assertion.Content())
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("POST", "/api/v1/assertions", bytes.NewReader(raw))
+	r.Header.Set("Content-Type", COSE_CONTENT_TYPE)
+
+	router.ServeHTTP(w, r)
+
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != assertion.Uri().ApiPath() {
+		t.Errorf("Unexpected response: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", assertion.Uri().ApiPath(), nil)
+
+	router.ServeHTTP(w, r)
+
+	if w.Body.String() != assertion.Content() {
+		t.Errorf("Unexpected response body: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", assertion.Uri().ApiPath(), nil)
+	r.Header.Set("Accept", COSE_CONTENT_TYPE)
+
+	router.ServeHTTP(w, r)
+
+	if !bytes.Equal(w.Body.Bytes(), raw) || w.Header().Get("Content-Type") != COSE_CONTENT_TYPE {
+		t.Errorf("Unexpected binary response: %s %x", w.Header().Get("Content-Type"), w.Body.Bytes())
+	}
+}
+
+func TestStatementTrustApi(t *testing.T) {
+	router := mux.NewRouter()
+	AddHandlers(router)
+
+	datastore.InitInMemoryDataStore()
+	assertions.PublicKeyResolver = datastore.ActiveDataStore
+	ctx := context.TODO()
+
+	entityUri := datastore.CreateEntityWithKey(ctx, "Test")
+	assertion, _ := datastore.CreateStatementAndAssertion(ctx, "test", entityUri, assertions.IsTrue, 1.0)
+	trust.DefaultRoots = trust.NewRoots(entityUri)
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", references.UriFromString(assertion.Subject).ApiPath()+"/trust", nil)
+
+	router.ServeHTTP(w, r)
+
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected content type: %s", w.Header().Get("Content-Type"))
+	}
+
+	var response struct {
+		Score       trust.Score
+		Explanation trust.Explanation
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	if err != nil {
+		t.Errorf("Error parsing response: %v", err)
+	}
+	if response.Score.Value != 1.0 {
+		t.Errorf("Unexpected score: %f", response.Score.Value)
+	}
+	if len(response.Explanation.Children) != 1 || response.Explanation.Children[0].Link != assertion.Uri().WebPath() {
+		t.Errorf("Unexpected explanation: %s", w.Body.String())
+	}
+
+	// Logged-in users get scores from their own trust roots, as on the web pages
+	sceptic := datastore.CreateEntityWithKey(ctx, "Sceptic")
+	privateKey, _ := datastore.FetchPrivateKey(sceptic)
+	datastore.CreateAssertion(ctx, references.UriFromString(assertion.Subject), sceptic, assertions.IsFalse, 1.0, privateKey)
+	user := auth.User{Id: "sceptical@example.com"}
+	user.AddTrustRoot(sceptic.String(), 1.0)
+	datastore.ActiveDataStore.StoreUser(ctx, user)
+	token, _ := auth.MakeUserJwt(user.Id, auth.UserJwtKey)
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", references.UriFromString(assertion.Subject).ApiPath()+"/trust", nil)
+	r.AddCookie(&http.Cookie{Name: "auth", Value: token})
+	router.ServeHTTP(w, r)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Score.Value != 0.0 {
+		t.Errorf("Unexpected score from the user's roots: %f", response.Score.Value)
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", references.MakeUri("0123456789abcdef", "statement").ApiPath()+"/trust", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Unexpected status for unknown statement: %d", w.Code)
+	}
+}
+
+func TestStatementTrustApiModels(t *testing.T) {
+	router := mux.NewRouter()
+	AddHandlers(router)
+
+	datastore.InitInMemoryDataStore()
+	assertions.PublicKeyResolver = datastore.ActiveDataStore
+	ctx := context.TODO()
+
+	entityUri := datastore.CreateEntityWithKey(ctx, "Test")
+	assertion, _ := datastore.CreateStatementAndAssertion(ctx, "test", entityUri, assertions.IsTrue, 1.0)
+	trust.DefaultRoots = trust.NewRoots(entityUri)
+	trustPath := references.UriFromString(assertion.Subject).ApiPath() + "/trust"
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", trustPath+"?model=beta", nil)
+	router.ServeHTTP(w, r)
+
+	var response struct {
+		Score trust.Score
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Score.Model != "beta" {
+		t.Errorf("Unexpected model: %s", response.Score.Model)
+	}
+	if !response.Score.HasInterval() {
+		t.Errorf("Beta model score has no credible interval: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", trustPath+"?model=astrology", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Unexpected status for unknown model: %d", w.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package assertions
+
+import (
+	"context"
+
+	"silvatek.uk/trustedassertions/internal/references"
+)
+
+// The assertions that qualify another assertion, which are found through the references to it.
+type Annotations struct {
+	Retraction *Assertion // The assertion by which its issuer withdrew the assertion, if it has been retracted
+	Timestamp  *Assertion // The earliest timestamp that the timestamp authority issued for the assertion, if any
+}
+
+// The categories of references that can be from an annotation. References that were stored
+// before categories were recorded with them have no category, so could be from either.
+var annotationCategories = map[string]bool{
+	"":                     true,
+	Retracts.String():      true,
+	IsTimestamped.String(): true,
+}
+
+// Finds the retraction and timestamp of an assertion, reading the references to the assertion
+// only once and fetching only the referring assertions that could be either.
+//
+// Only the entity that issued an assertion can retract it, so retractions by anyone else are
+// ignored, as are retractions that are not in force. Timestamps issued by anyone other than the
+// timestamp authority are ignored, as are those without an issue time.
+func AnnotationsOf(ctx context.Context, resolver Resolver, assertion Assertion) Annotations {
+	var annotations Annotations
+	uri := assertion.Uri()
+	issuer := references.UriFromString(assertion.Issuer)
+
+	refs, err := resolver.FetchRefs(ctx, uri)
+	if err != nil {
+		return annotations
+	}
+
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if !IsAssertionKind(ref.Source.Kind()) || !annotationCategories[ref.Category] || seen[ref.Source.Escaped()] {
+			continue
+		}
+		seen[ref.Source.Escaped()] = true
+
+		annotation, err := resolver.FetchAssertion(ctx, ref.Source)
+		if err != nil || references.UriFromString(annotation.Subject).Hash() != uri.Hash() {
+			continue
+		}
+
+		switch {
+		case AssertionTypeOf(annotation.Category) == Retracts:
+			if annotations.Retraction == nil && annotation.IsActive() &&
+				references.UriFromString(annotation.Issuer).Hash() == issuer.Hash() {
+				annotations.Retraction = &annotation
+			}
+		case IsTimestamp(annotation) && annotation.IssuedAt != nil:
+			if annotations.Timestamp == nil || annotation.IssuedAt.Before(annotations.Timestamp.IssuedAt.Time) {
+				annotations.Timestamp = &annotation
+			}
+		}
+	}
+
+	return annotations
+}
+
+// Finds the retraction and timestamp of the assertion, and applies the timestamp so that its
+// trusted time is used in place of the claimed issue time.
+func (a *Assertion) Annotate(ctx context.Context, resolver Resolver) Annotations {
+	annotations := AnnotationsOf(ctx, resolver, *a)
+	if annotations.Timestamp != nil {
+		a.timestamp = annotations.Timestamp.IssuedAt.Time
+	}
+	return annotations
+}
@@ -2,10 +2,10 @@ package assertions
 
 import (
 	"context"
-	"crypto/rsa"
+	"crypto"
 	"errors"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"silvatek.uk/trustedassertions/internal/entities"
@@ -13,7 +13,6 @@ import (
 	"silvatek.uk/trustedassertions/internal/references"
 )
 
-const DEFAULT_AUDIENCE = "trustedassertions:0.1/any"
 const UNDEFINED_CATEGORY = "Undefined"
 
 type Assertion struct {
@@ -21,31 +20,78 @@ type Assertion struct {
 	Category   string             `json:"category,omitempty"`
 	Confidence float32            `json:"confidence,omitempty"`
 	Object     string             `json:"object,omitempty"`
+	Since      *jwt.NumericDate   `json:"since,omitempty"` // For IsCompromised, when the key was first compromised
+	Basis      []string           `json:"basis,omitempty"` // URIs of other assertions that support this assertion
 	content    string             `json:"-"`
+	format     Format             `json:"-"`
 	uri        references.HashUri `json:"-"`
 	summary    string             `json:"-"`
+	timestamp  time.Time          `json:"-"` // When the timestamp authority received the assertion, if known
 }
 
 type AssertionType string
 
+// The assertion types that have a particular meaning to the framework. Other types can be added
+// through the category registry.
 const (
-	IsTrue  AssertionType = "IsTrue"
-	IsFalse AssertionType = "IsFalse"
-	Unknown AssertionType = "Unknown"
+	IsTrue        AssertionType = "IsTrue"
+	IsFalse       AssertionType = "IsFalse"
+	IsTrusted     AssertionType = "IsTrusted"
+	IsCompromised AssertionType = "IsCompromised"
+	IsEndorsed    AssertionType = "IsEndorsed"
+	IsDisputed    AssertionType = "IsDisputed"
+	Retracts      AssertionType = "Retracts"
+	Replaces      AssertionType = "Replaces"
+	IsSameAs      AssertionType = "IsSameAs"
+	IsTimestamped AssertionType = "IsTimestamped"
+	Unknown       AssertionType = "Unknown"
 )
 
-var AssertionTypes = []AssertionType{IsTrue, IsFalse}
-
 func (at AssertionType) String() string {
 	return string(at)
 }
 
-func AssertionTypeOf(s string) AssertionType {
-	for _, at := range AssertionTypes {
-		if at.String() == s {
-			return at
+// Returns the registered category for this type of assertion.
+func (at AssertionType) Category() Category {
+	category, _ := CategoryOf(at.String())
+	return category
+}
+
+func (at AssertionType) Description() string {
+	return at.DescriptionIn(DefaultLanguage)
+}
+
+func (at AssertionType) DescriptionIn(language string) string {
+	return CategoryDescription(at.String(), language)
+}
+
+// Returns how assertions of this type are used by trust models.
+func (at AssertionType) Effect() TrustEffect {
+	return at.Category().Effect
+}
+
+// Whether assertions of this type relate their subject to an object.
+func (at AssertionType) HasObject() bool {
+	return at.Category().Object != ""
+}
+
+// Returns the assertion types that can be used for a subject of the specified kind, in the order
+// that their categories were registered. Internal categories, which only the server uses, are left out.
+func CategoriesFor(kind string) []AssertionType {
+	types := make([]AssertionType, 0)
+	for _, category := range Categories() {
+		if category.AllowsSubject(kind) && !category.Internal {
+			types = append(types, AssertionType(category.Name))
 		}
 	}
+	return types
+}
+
+// Returns the assertion type with the specified name, or Unknown if there is no such category.
+func AssertionTypeOf(s string) AssertionType {
+	if _, found := CategoryOf(s); found {
+		return AssertionType(s)
+	}
 	return Unknown
 }
 
@@ -65,15 +111,55 @@ func NewAssertion(category AssertionType) Assertion {
 }
 
 // Returns the public key to be used to verify the specified JWT token.
-// The token issuer should be the URI of an entity, and that entity is fetched using the PublicKeyResolver.
 func verificationKey(token *jwt.Token) (interface{}, error) {
-	entityUri, _ := token.Claims.GetIssuer()
-	entity, err := PublicKeyResolver.FetchEntity(context.Background(), references.UriFromString(entityUri))
-	return entity.PublicKey, err
+	assertion, ok := token.Claims.(*Assertion)
+	if !ok {
+		return nil, errors.New("token claims are not an assertion")
+	}
+	return issuerKey(assertion)
 }
 
+// Returns the public key to be used to verify an assertion, in whichever format it was signed.
+// The assertion issuer should be the URI of an entity, and that entity is fetched using the PublicKeyResolver.
+// If the entity's certificate was issued by an organisation, the chain of issuers must be valid.
+//
+// Assertions issued after the issuer declared its own key compromised are rejected, apart from
+// further IsCompromised assertions, which are always accepted. If the assertion has been timestamped,
+// the time it was received is used rather than the time it claims.
+func issuerKey(assertion *Assertion) (interface{}, error) {
+	ctx := context.Background()
+	entityUri := references.UriFromString(assertion.Issuer)
+	entity, err := PublicKeyResolver.FetchEntity(ctx, entityUri)
+	if err != nil {
+		return entity.PublicKey, err
+	}
+
+	chain, err := IssuerChain(ctx, PublicKeyResolver, entity)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkValidity(*assertion, entity, chain); err != nil {
+		return nil, err
+	}
+	if AssertionTypeOf(assertion.Category) != IsCompromised {
+		if compromise, found := SelfCompromise(ctx, PublicKeyResolver, entityUri); found {
+			assertion.ApplyTimestamp(ctx, PublicKeyResolver)
+			if compromise.Covers(*assertion) {
+				return nil, ErrCompromised
+			}
+		}
+	}
+
+	return entity.PublicKey, nil
+}
+
+// Parses and verifies the signed content of an assertion, which can be a compact JWT or a
+// COSE_Sign1 structure encoded by EncodeCose.
 func (a *Assertion) ParseContent(content string) error {
 	a.content = content
+	a.uri = references.HashUri{}
+	a.timestamp = time.Time{}
 
 	if content == "" {
 		return errors.New("unable to parse empty JWT")
@@ -81,11 +167,39 @@ func (a *Assertion) ParseContent(content string) error {
 
 	a.RegisteredClaims = &jwt.RegisteredClaims{}
 
-	_, err := jwt.ParseWithClaims(content, a, verificationKey)
+	if IsCose(content) {
+		a.format = CoseFormat
+		if err := a.parseCose(content); err != nil {
+			return err
+		}
+		return a.checkAudience()
+	}
+
+	a.format = JwtFormat
+
+	// Expired and not-yet-valid assertions are still parsed, and are treated as inactive by their users
+	_, err := jwt.ParseWithClaims(content, a, verificationKey, jwt.WithoutClaimsValidation(), jwt.WithValidMethods(signingMethodNames))
+	if err != nil {
+		return err
+	}
+
+	// Assertions meant for other audiences are rejected, even though their signatures are valid
+	return a.checkAudience()
+}
 
-	return err
+// Reads the claims of an assertion in either format without verifying its signature, which is only
+// safe for deciding whether the assertion needs to be verified.
+func ParseUnverified(content string) (Assertion, error) {
+	claims := Assertion{RegisteredClaims: &jwt.RegisteredClaims{}}
+	if IsCose(content) {
+		_, err := claims.parseCoseClaims(content)
+		return claims, err
+	}
+	_, _, err := jwt.NewParser().ParseUnverified(content, &claims)
+	return claims, err
 }
 
+// Parses and verifies an assertion, which can be a compact JWT or an encoded COSE_Sign1 structure.
 func ParseAssertionJwt(token string) (Assertion, error) {
 	assertion := Assertion{
 		RegisteredClaims: &jwt.RegisteredClaims{},
@@ -100,14 +214,33 @@ func ParseAssertionJwt(token string) (Assertion, error) {
 	return assertion, err
 }
 
-func (a *Assertion) MakeJwt(privateKey *rsa.PrivateKey) {
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, a)
+// The names of the JWT signing methods that assertions can be verified with, one for each supported key algorithm.
+var signingMethodNames = func() []string {
+	var names []string
+	for _, algorithm := range entities.KeyAlgorithms() {
+		if method, ok := entities.SigningMethodOf(algorithm); ok {
+			names = append(names, method.Alg())
+		}
+	}
+	return names
+}()
+
+// Signs the assertion with an RSA, ECDSA P-256 or Ed25519 private key, using the matching JWT signing method.
+func (a *Assertion) MakeJwt(privateKey crypto.Signer) {
+	method, ok := entities.SigningMethodOf(entities.AlgorithmOf(privateKey))
+	if !ok {
+		log.Errorf("Unsupported key type for signing JWT: %T", privateKey)
+		return
+	}
+	token := jwt.NewWithClaims(method, a)
 	signed, err := token.SignedString(privateKey)
 	if err != nil {
 		log.Errorf("Error creating signed JWT")
 		return
 	}
 	a.content = signed
+	a.format = JwtFormat
+	a.uri = references.HashUri{}
 }
 
 func (a *Assertion) Uri() references.HashUri {
@@ -121,10 +254,22 @@ func (a *Assertion) Uri() references.HashUri {
 	return a.uri
 }
 
+// Returns the type of the assertion, which depends on the format of its signed content.
 func (a *Assertion) Type() string {
+	if a.format == CoseFormat {
+		return COSE_TYPE
+	}
 	return "Assertion"
 }
 
+// Returns the format of the assertion's signed content.
+func (a Assertion) Format() Format {
+	if a.format == "" {
+		return JwtFormat
+	}
+	return a.format
+}
+
 func (a *Assertion) Content() string {
 	return a.content
 }
@@ -152,6 +297,12 @@ func (a Assertion) References() []references.HashUri {
 	if a.RegisteredClaims.Subject != "" {
 		refs = append(refs, references.UriFromString(a.RegisteredClaims.Subject))
 	}
+	if a.Object != "" {
+		refs = append(refs, references.UriFromString(a.Object))
+	}
+	for _, basis := range a.Basis {
+		refs = append(refs, references.UriFromString(basis))
+	}
 	return refs
 }
 
@@ -159,20 +310,14 @@ func (a *Assertion) SetAssertingEntity(entity entities.Entity) {
 	a.RegisteredClaims.Issuer = entity.Uri().String()
 }
 
+// Returns the description of a category in the specified language, or the category itself if it
+// is not registered.
 func CategoryDescription(category string, language string) string {
-	if strings.HasPrefix(language, "en") {
-		switch category {
-		case "IsTrue":
-			return "is true"
-		case "IsFalse":
-			return "is false"
-		default:
-			return category
-		}
-
-	} else {
+	registered, found := CategoryOf(category)
+	if !found {
 		return category
 	}
+	return registered.DescriptionIn(language)
 }
 
 func SummariseAssertion(ctx context.Context, assertion Assertion, cache references.ReferenceMap, resolver Resolver) string {
@@ -187,21 +332,48 @@ func SummariseAssertion(ctx context.Context, assertion Assertion, cache referenc
 	if found {
 		issuerName = cached.Summary()
 	} else {
-
 		entity, _ := resolver.FetchEntity(ctx, issuerUri)
 		issuerName = entity.Summary()
 	}
 
-	var subjectSummary string
+	subjectSummary := summaryOf(ctx, references.UriFromString(assertion.Subject), cache, resolver)
+	description := CategoryDescription(assertion.Category, DefaultLanguage)
 
-	subjectUri := references.UriFromString(assertion.Subject)
-	cached, found = cache[subjectUri]
+	if assertion.Object != "" {
+		objectSummary := summaryOf(ctx, references.UriFromString(assertion.Object), cache, resolver)
+		return fmt.Sprintf("%s claims that '%s' %s '%s'", issuerName, subjectSummary, description, objectSummary)
+	}
+
+	return fmt.Sprintf("%s claims that '%s' %s", issuerName, subjectSummary, description)
+}
+
+// Returns the summary of a referenced value, using the cache where possible.
+// Assertions about other assertions include a summary of the assertion they are about.
+func summaryOf(ctx context.Context, uri references.HashUri, cache references.ReferenceMap, resolver Resolver) string {
+	cached, found := cache[uri]
 	if found {
-		subjectSummary = cached.Summary()
-	} else {
-		subject, _ := resolver.FetchStatement(ctx, subjectUri)
-		subjectSummary = subject.Summary()
+		if assertion, ok := cached.(*Assertion); ok && assertion.RegisteredClaims != nil {
+			return SummariseAssertion(ctx, *assertion, cache, resolver)
+		}
+		return cached.Summary()
 	}
 
-	return fmt.Sprintf("%s claims that '%s' %s", issuerName, subjectSummary, CategoryDescription(assertion.Category, "en"))
+	kind := TypedUri(ctx, resolver, uri).Kind()
+	switch {
+	case kind == "entity":
+		entity, _ := resolver.FetchEntity(ctx, uri)
+		return entity.Summary()
+	case kind == "document":
+		document, _ := resolver.FetchDocument(ctx, uri)
+		return document.Summary()
+	case IsAssertionKind(kind):
+		assertion, err := resolver.FetchAssertion(ctx, uri)
+		if err != nil || assertion.RegisteredClaims == nil {
+			return "Unverified assertion"
+		}
+		return SummariseAssertion(ctx, assertion, cache, resolver)
+	default:
+		statement, _ := resolver.FetchStatement(ctx, uri)
+		return statement.Summary()
+	}
 }
@@ -0,0 +1,101 @@
+package assertions
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// The prefix of every Trusted Assertions audience, which is followed by the protocol version and scope.
+const AUDIENCE_PREFIX = "trustedassertions:"
+
+// The protocol version used for new assertions.
+const PROTOCOL_VERSION = "0.1"
+
+// The scope of an audience for assertions meant for any recipient.
+const ANY_SCOPE = "any"
+
+const DEFAULT_AUDIENCE = AUDIENCE_PREFIX + PROTOCOL_VERSION + "/" + ANY_SCOPE
+
+// Returned when parsing an assertion that is not meant for an audience this server supports.
+var ErrUnsupportedAudience = errors.New("assertion audience not supported")
+
+// ProtocolVersion is a version of the assertion claim format that can be read.
+//
+// When the claim format changes, the new version becomes PROTOCOL_VERSION and each older version
+// that can still be read is registered with an Upgrade function that converts its claims into the
+// current format, so that assertions of several versions can be used side by side.
+type ProtocolVersion struct {
+	Version string
+	Upgrade func(*Assertion) error // Converts the claims to the current format, or nil if they are unchanged
+}
+
+var protocols struct {
+	sync.RWMutex
+	versions map[string]ProtocolVersion
+}
+
+func init() {
+	protocols.versions = map[string]ProtocolVersion{
+		PROTOCOL_VERSION: {Version: PROTOCOL_VERSION},
+	}
+}
+
+// Adds a protocol version that assertions can be read in, replacing any existing registration of that version.
+func RegisterProtocolVersion(version ProtocolVersion) {
+	protocols.Lock()
+	defer protocols.Unlock()
+	protocols.versions[version.Version] = version
+}
+
+// Returns the protocol versions that assertions can be read in, in alphabetical order.
+func SupportedVersions() []string {
+	protocols.RLock()
+	defer protocols.RUnlock()
+
+	versions := make([]string, 0, len(protocols.versions))
+	for version := range protocols.versions {
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	return versions
+}
+
+// Returns the protocol version that an audience belongs to, or an error if the audience is not
+// for any recipient of a supported version.
+func protocolOf(audience string) (ProtocolVersion, error) {
+	versioned, found := strings.CutPrefix(audience, AUDIENCE_PREFIX)
+	if !found {
+		return ProtocolVersion{}, fmt.Errorf("%w: %s", ErrUnsupportedAudience, audience)
+	}
+	version, scope, _ := strings.Cut(versioned, "/")
+	if scope != ANY_SCOPE {
+		return ProtocolVersion{}, fmt.Errorf("%w: scope %s", ErrUnsupportedAudience, audience)
+	}
+
+	protocols.RLock()
+	defer protocols.RUnlock()
+	protocol, found := protocols.versions[version]
+	if !found {
+		return ProtocolVersion{}, fmt.Errorf("%w: version %s", ErrUnsupportedAudience, audience)
+	}
+	return protocol, nil
+}
+
+// Checks that the assertion has a single audience of a supported protocol version, and upgrades
+// its claims from that version to the current format.
+func (a *Assertion) checkAudience() error {
+	if a.RegisteredClaims == nil || len(a.Audience) != 1 {
+		return fmt.Errorf("%w: must have a single audience", ErrUnsupportedAudience)
+	}
+	protocol, err := protocolOf(a.Audience[0])
+	if err != nil {
+		return err
+	}
+	if protocol.Upgrade != nil {
+		return protocol.Upgrade(a)
+	}
+	return nil
+}
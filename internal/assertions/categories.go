@@ -0,0 +1,170 @@
+package assertions
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TrustEffect is how assertions of a category are used by trust models.
+type TrustEffect string
+
+const (
+	Supports TrustEffect = "supports" // Evidence that the subject statement is true
+	Opposes  TrustEffect = "opposes"  // Evidence that the subject statement is not true
+	Trusts   TrustEffect = "trusts"   // Trust passes from the issuer to the subject entity
+	NoEffect TrustEffect = ""         // Not used directly as evidence or trust
+)
+
+// Category describes a category of assertion: what it can be about, how it affects trust, and how
+// it is described in each locale.
+type Category struct {
+	Name         string            `json:"name"`
+	Subjects     []string          `json:"subjects"`         // The kinds of subject that the category can be used for
+	Object       string            `json:"object,omitempty"` // The kind of object, "subject" for the same kind as the subject, or empty for none
+	Effect       TrustEffect       `json:"effect,omitempty"`
+	Descriptions map[string]string `json:"descriptions"`       // Descriptions by locale, such as "en" or "fr-CA", case insensitive
+	Internal     bool              `json:"internal,omitempty"` // Whether only the server issues assertions of the category, so it is not offered to users
+}
+
+// The language used for descriptions when no other has been requested, such as in assertion summaries.
+var DefaultLanguage = "en"
+
+//go:embed categories.json
+var defaultCategories []byte
+
+var registry struct {
+	sync.RWMutex
+	categories []Category
+}
+
+func init() {
+	categories, err := ParseCategories(defaultCategories)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default categories: %v", err))
+	}
+	registry.categories = categories
+}
+
+// Parses a JSON list of categories.
+func ParseCategories(data []byte) ([]Category, error) {
+	var categories []Category
+	if err := json.Unmarshal(data, &categories); err != nil {
+		return nil, err
+	}
+	for n, category := range categories {
+		descriptions := make(map[string]string, len(category.Descriptions))
+		for language, description := range category.Descriptions {
+			descriptions[strings.ToLower(language)] = description
+		}
+		categories[n].Descriptions = descriptions
+
+		if category.Name == "" || len(category.Subjects) == 0 {
+			return nil, fmt.Errorf("category must have a name and at least one subject kind: %v", category)
+		}
+		switch category.Effect {
+		case Supports, Opposes, Trusts, NoEffect:
+		default:
+			return nil, fmt.Errorf("unknown trust effect for %s: %s", category.Name, category.Effect)
+		}
+	}
+	return categories, nil
+}
+
+// Loads categories from a JSON file into the registry. Categories with the same name as one
+// already registered replace it, and others are added.
+func LoadCategories(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	categories, err := ParseCategories(data)
+	if err != nil {
+		return err
+	}
+	for _, category := range categories {
+		RegisterCategory(category)
+	}
+	return nil
+}
+
+// Adds a category to the registry, replacing any existing category with the same name.
+func RegisterCategory(category Category) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	for n, existing := range registry.categories {
+		if existing.Name == category.Name {
+			registry.categories[n] = category
+			return
+		}
+	}
+	registry.categories = append(registry.categories, category)
+}
+
+// Returns all the registered categories, in the order they were registered.
+func Categories() []Category {
+	registry.RLock()
+	defer registry.RUnlock()
+	return slices.Clone(registry.categories)
+}
+
+// Returns the registered category with the specified name.
+func CategoryOf(name string) (Category, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, category := range registry.categories {
+		if category.Name == name {
+			return category, true
+		}
+	}
+	return Category{}, false
+}
+
+// Whether the category can be used for a subject of the specified kind.
+func (c Category) AllowsSubject(kind string) bool {
+	return slices.Contains(c.Subjects, categoryKind(kind))
+}
+
+// Returns the kind that categories use for subjects and objects of the specified kind. Assertions
+// have the same categories whichever format they are signed in.
+func categoryKind(kind string) string {
+	kind = strings.ToLower(kind)
+	if IsAssertionKind(kind) {
+		return "assertion"
+	}
+	return kind
+}
+
+// Returns the kind of object required for a subject of the specified kind, or an empty string if
+// the category has no object.
+func (c Category) ObjectKind(subjectKind string) string {
+	if c.Object == "subject" {
+		return categoryKind(subjectKind)
+	}
+	return c.Object
+}
+
+// Whether an assertion in the category about a subject of the specified kind can have an object of
+// the specified kind, which is empty for an assertion without an object.
+func (c Category) AllowsObject(subjectKind string, objectKind string) bool {
+	return c.ObjectKind(subjectKind) == categoryKind(objectKind)
+}
+
+// Returns the description of the category in the specified language, falling back to the base
+// language (such as "en" for "en-GB") and then to the name of the category.
+func (c Category) DescriptionIn(language string) string {
+	language = strings.ToLower(language)
+	if description, found := c.Descriptions[language]; found {
+		return description
+	}
+	base, _, _ := strings.Cut(language, "-")
+	if description, found := c.Descriptions[base]; found {
+		return description
+	}
+	return c.Name
+}
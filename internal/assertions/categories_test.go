@@ -0,0 +1,73 @@
+package assertions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func withCategories(t *testing.T) {
+	saved := Categories()
+	t.Cleanup(func() {
+		registry.Lock()
+		registry.categories = saved
+		registry.Unlock()
+	})
+}
+
+func TestDefaultCategories(t *testing.T) {
+	if IsTrue.Effect() != Supports || IsFalse.Effect() != Opposes || IsTrusted.Effect() != Trusts || Replaces.Effect() != NoEffect {
+		t.Error("Unexpected trust effects for default categories")
+	}
+	if AssertionTypeOf("IsMisleading").Effect() != Opposes {
+		t.Error("IsMisleading should count against a statement")
+	}
+	if Replaces.Category().ObjectKind("Document") != "document" || IsTrue.Category().ObjectKind("statement") != "" {
+		t.Error("Unexpected object kinds")
+	}
+}
+
+func TestLoadCategories(t *testing.T) {
+	withCategories(t)
+
+	path := filepath.Join(t.TempDir(), "categories.json")
+	content := `[
+		{"name": "IsExaggerated", "subjects": ["statement"], "effect": "opposes", "descriptions": {"en": "is exaggerated", "fr-CA": "est exagéré"}},
+		{"name": "IsTrue", "subjects": ["statement", "document"], "effect": "supports", "descriptions": {"en": "is correct"}}
+	]`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadCategories(path); err != nil {
+		t.Fatalf("Error loading categories: %v", err)
+	}
+
+	exaggerated := AssertionTypeOf("IsExaggerated")
+	if exaggerated == Unknown || exaggerated.Effect() != Opposes {
+		t.Errorf("New category not registered: %v", exaggerated.Category())
+	}
+	if exaggerated.DescriptionIn("fr-ca") != "est exagéré" {
+		t.Errorf("Unexpected localised description: %s", exaggerated.DescriptionIn("fr-ca"))
+	}
+	if IsTrue.Description() != "is correct" || !IsTrue.Category().AllowsSubject("document") {
+		t.Errorf("Existing category not replaced: %v", IsTrue.Category())
+	}
+	if AssertionTypeOf("IsFalse") != IsFalse {
+		t.Error("Categories not in the file should still be registered")
+	}
+}
+
+func TestParseCategoriesErrors(t *testing.T) {
+	invalid := []string{
+		`not json`,
+		`[{"name": "", "subjects": ["statement"]}]`,
+		`[{"name": "IsOdd", "subjects": []}]`,
+		`[{"name": "IsOdd", "subjects": ["statement"], "effect": "confuses"}]`,
+	}
+	for _, content := range invalid {
+		if _, err := ParseCategories([]byte(content)); err == nil {
+			t.Errorf("Expected error parsing categories: %s", content)
+		}
+	}
+}
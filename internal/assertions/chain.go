@@ -0,0 +1,99 @@
+package assertions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"silvatek.uk/trustedassertions/internal/entities"
+	"silvatek.uk/trustedassertions/internal/references"
+)
+
+// Returned when an entity's chain of issuing organisations cannot be verified.
+var ErrInvalidChain = errors.New("entity certificate chain not valid")
+
+// The maximum number of issuing organisations that are followed from an entity.
+var MaxChainLength = 8
+
+// Follows the chain of organisations that issued an entity's certificate back to a self-signed
+// organisation, checking that each certificate was issued by the next entity in the chain.
+//
+// The chain starts with the entity's parent and ends with the self-signed organisation, and is
+// empty for a self-signed entity.
+func IssuerChain(ctx context.Context, resolver Resolver, entity entities.Entity) ([]entities.Entity, error) {
+	chain := make([]entities.Entity, 0)
+	visited := map[string]bool{entity.Uri().Hash(): true}
+
+	current := entity
+	for !current.IsSelfSigned() {
+		if len(chain) >= MaxChainLength {
+			return chain, fmt.Errorf("%w: more than %d issuers", ErrInvalidChain, MaxChainLength)
+		}
+		if visited[current.Parent.Hash()] {
+			return chain, fmt.Errorf("%w: loop at %s", ErrInvalidChain, current.Parent)
+		}
+		visited[current.Parent.Hash()] = true
+
+		parent, err := resolver.FetchEntity(ctx, current.Parent)
+		if err != nil {
+			return chain, fmt.Errorf("%w: issuer %s: %v", ErrInvalidChain, current.Parent, err)
+		}
+		if err := current.VerifyIssuedBy(parent); err != nil {
+			return chain, fmt.Errorf("%w: %v", ErrInvalidChain, err)
+		}
+		chain = append(chain, parent)
+		current = parent
+	}
+
+	return chain, nil
+}
+
+// Finds the entities whose certificates were issued by an organisation.
+func MembersOf(ctx context.Context, resolver Resolver, organisation references.HashUri) []entities.Entity {
+	members := make([]entities.Entity, 0)
+
+	parent, err := resolver.FetchEntity(ctx, organisation)
+	if err != nil {
+		return members
+	}
+	refs, err := resolver.FetchRefs(ctx, organisation)
+	if err != nil {
+		return members
+	}
+
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.Source.Kind() != "entity" || seen[ref.Source.Hash()] {
+			continue
+		}
+		seen[ref.Source.Hash()] = true
+
+		member, err := resolver.FetchEntity(ctx, ref.Source)
+		if err != nil || member.VerifyIssuedBy(parent) != nil {
+			continue
+		}
+		members = append(members, member)
+	}
+
+	return members
+}
+
+// Checks that the certificates of the entity that issued an assertion, and of each organisation in
+// its issuer chain, were valid at the time the assertion was issued.
+//
+// Assertions without an issue time cannot be checked, as there is no way of telling when they were signed.
+func checkValidity(assertion Assertion, issuer entities.Entity, chain []entities.Entity) error {
+	if assertion.RegisteredClaims == nil || assertion.IssuedAt == nil {
+		return nil
+	}
+	issued := assertion.IssuedAt.Time
+	if err := issuer.CheckValidAt(issued); err != nil {
+		return err
+	}
+	for _, parent := range chain {
+		if err := parent.CheckValidAt(issued); err != nil {
+			return err
+		}
+	}
+	return nil
+}
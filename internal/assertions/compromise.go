@@ -0,0 +1,88 @@
+package assertions
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"silvatek.uk/trustedassertions/internal/references"
+)
+
+// Returned when verifying an assertion issued after its issuer's key was declared compromised.
+var ErrCompromised = errors.New("issuer key declared compromised")
+
+// Returns the time from which an IsCompromised assertion says the key of its subject was compromised.
+// If the assertion does not say, the key is taken to have been compromised when the assertion was issued.
+func (a Assertion) CompromisedSince() time.Time {
+	if a.Since != nil {
+		return a.Since.Time
+	}
+	if a.RegisteredClaims != nil && a.IssuedAt != nil {
+		return a.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
+// Whether an assertion was issued after the key compromise declared by this IsCompromised assertion.
+// The trusted time of a timestamped assertion is used in place of the time it claims, as a stolen key
+// can be used to sign backdated assertions. Assertions with no issue time cannot be shown to predate
+// the compromise, so are always covered.
+func (a Assertion) Covers(other Assertion) bool {
+	issued := other.IssueTime()
+	if issued.IsZero() {
+		return true
+	}
+	return !issued.Before(a.CompromisedSince())
+}
+
+// Finds the earliest compromise that an entity has declared of its own key.
+//
+// This is checked whenever an assertion is verified, so only the references to the entity from
+// IsCompromised assertions are fetched, using the category stored with each reference. Their claims
+// are read without being verified to find the entity's own declarations, and only those are verified.
+func SelfCompromise(ctx context.Context, resolver Resolver, entityUri references.HashUri) (Assertion, bool) {
+	var earliest Assertion
+	found := false
+
+	refs, err := resolver.FetchRefsOfCategory(ctx, entityUri, IsCompromised.String())
+	if err != nil {
+		return earliest, false
+	}
+
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if !IsAssertionKind(ref.Source.Kind()) || seen[ref.Source.Escaped()] {
+			continue
+		}
+		seen[ref.Source.Escaped()] = true
+
+		content, err := resolver.FetchContent(ctx, ref.Source)
+		if err != nil {
+			continue
+		}
+		claims, err := ParseUnverified(content)
+		if err != nil {
+			continue
+		}
+		if AssertionTypeOf(claims.Category) != IsCompromised ||
+			references.UriFromString(claims.Issuer).Hash() != entityUri.Hash() ||
+			references.UriFromString(claims.Subject).Hash() != entityUri.Hash() {
+			continue
+		}
+
+		assertion, err := ParseAssertionJwt(content)
+		if err != nil {
+			log.DebugfX(ctx, "Ignoring unverified compromise %s: %v", ref.Source, err)
+			continue
+		}
+		if !assertion.IsActive() {
+			continue
+		}
+		if !found || assertion.CompromisedSince().Before(earliest.CompromisedSince()) {
+			earliest = assertion
+			found = true
+		}
+	}
+
+	return earliest, found
+}
@@ -0,0 +1,357 @@
+package assertions
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"silvatek.uk/trustedassertions/internal/entities"
+	"silvatek.uk/trustedassertions/internal/references"
+)
+
+// Assertions can be signed as COSE_Sign1 structures (RFC 9052) as well as JWTs. The payload is a CBOR
+// Web Token claims set (RFC 8392) carrying the same claims as the JWT, with the registered claims
+// under their integer keys and the others under their JWT names. This is much smaller than a JWT,
+// which suits constrained clients and QR codes.
+//
+// The signed content of a COSE assertion is the CBOR structure encoded as unpadded base64url, so that
+// it can be stored and exchanged as text in the same way as a JWT.
+
+// The type of assertions signed as COSE_Sign1 structures, and the kind of their HashUris.
+const COSE_TYPE = "CoseAssertion"
+const COSE_KIND = "coseassertion"
+
+// Format is the format of an assertion's signed content.
+type Format string
+
+const (
+	JwtFormat  Format = "JWT"
+	CoseFormat Format = "COSE"
+)
+
+// Returns the formats that assertions can be signed in.
+func Formats() []Format {
+	return []Format{JwtFormat, CoseFormat}
+}
+
+// Returns the format with the specified name, ignoring case.
+func FormatOf(name string) (Format, error) {
+	for _, format := range Formats() {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported assertion format: %s", name)
+}
+
+// Returned when parsing content that is not a valid COSE_Sign1 assertion.
+var ErrInvalidCose = errors.New("not a valid COSE_Sign1 assertion")
+
+const coseSign1Tag = 18
+const coseHeaderAlgorithm = 1
+
+// The COSE algorithm identifiers for each supported key algorithm.
+var coseAlgorithms = map[entities.KeyAlgorithm]int64{
+	entities.RSA:     -257, // RS256
+	entities.ECDSA:   -7,   // ES256
+	entities.Ed25519: -8,   // EdDSA
+}
+
+// The integer keys of the registered claims in a CBOR Web Token.
+var cwtClaimKeys = map[string]uint64{
+	"iss": 1,
+	"sub": 2,
+	"aud": 3,
+	"exp": 4,
+	"nbf": 5,
+	"iat": 6,
+	"jti": 7,
+}
+
+type coseSign1 struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   []byte
+	Unprotected map[int64]any
+	Payload     []byte
+	Signature   []byte
+}
+
+// Signed content is encoded deterministically, so that the same claims always have the same URI.
+var coseEncoding, _ = cbor.CoreDetEncOptions().EncMode()
+var coseDecoding, _ = cbor.DecOptions{DupMapKey: cbor.DupMapKeyEnforcedAPF}.DecMode()
+
+// Whether the content is an encoded COSE_Sign1 structure.
+func IsCose(content string) bool {
+	raw, err := DecodeCose(content)
+	if err != nil || len(raw) < 2 || raw[0] != 0xd2 || raw[1] != 0x84 { // Tag 18, then an array of four items
+		return false
+	}
+	return coseDecoding.Wellformed(raw) == nil
+}
+
+// Encodes a binary COSE_Sign1 structure as the text content of an assertion.
+func EncodeCose(raw []byte) string {
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decodes the text content of an assertion into a binary COSE_Sign1 structure.
+func DecodeCose(content string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(content)
+}
+
+// Whether items of the specified kind are assertions, in either format.
+func IsAssertionKind(kind string) bool {
+	return kind == "assertion" || kind == COSE_KIND
+}
+
+// Signs the assertion with the private key in the specified format.
+func (a *Assertion) Sign(privateKey crypto.Signer, format Format) {
+	if format == CoseFormat {
+		a.MakeCose(privateKey)
+	} else {
+		a.MakeJwt(privateKey)
+	}
+}
+
+// Signs the assertion with an RSA, ECDSA P-256 or Ed25519 private key as a COSE_Sign1 structure.
+func (a *Assertion) MakeCose(privateKey crypto.Signer) {
+	algorithm := entities.AlgorithmOf(privateKey)
+	method, ok := entities.SigningMethodOf(algorithm)
+	if !ok {
+		log.Errorf("Unsupported key type for signing COSE: %T", privateKey)
+		return
+	}
+
+	protected, err := coseEncoding.Marshal(map[int64]int64{coseHeaderAlgorithm: coseAlgorithms[algorithm]})
+	if err != nil {
+		log.Errorf("Error encoding COSE header: %v", err)
+		return
+	}
+	payload, err := a.cwtClaims()
+	if err != nil {
+		log.Errorf("Error encoding COSE claims: %v", err)
+		return
+	}
+	toBeSigned, err := sigStructure(protected, payload)
+	if err != nil {
+		log.Errorf("Error encoding COSE signature structure: %v", err)
+		return
+	}
+	signature, err := method.Sign(string(toBeSigned), privateKey)
+	if err != nil {
+		log.Errorf("Error signing COSE assertion")
+		return
+	}
+
+	message := coseSign1{Protected: protected, Unprotected: map[int64]any{}, Payload: payload, Signature: signature}
+	raw, err := coseEncoding.Marshal(cbor.Tag{Number: coseSign1Tag, Content: message})
+	if err != nil {
+		log.Errorf("Error encoding COSE assertion: %v", err)
+		return
+	}
+
+	a.content = EncodeCose(raw)
+	a.format = CoseFormat
+	a.uri = references.HashUri{}
+}
+
+// Parses the claims of a COSE_Sign1 assertion and verifies its signature with the issuer's key.
+func (a *Assertion) parseCose(content string) error {
+	message, err := a.parseCoseClaims(content)
+	if err != nil {
+		return err
+	}
+	method, err := coseSigningMethod(message.Protected)
+	if err != nil {
+		return err
+	}
+
+	key, err := issuerKey(a)
+	if err != nil {
+		return err
+	}
+	toBeSigned, err := sigStructure(message.Protected, message.Payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCose, err)
+	}
+	return method.Verify(string(toBeSigned), message.Signature, key)
+}
+
+// Decodes a COSE_Sign1 structure and sets the claims of the assertion from it, without verifying its signature.
+func (a *Assertion) parseCoseClaims(content string) (coseSign1, error) {
+	var message coseSign1
+	raw, err := DecodeCose(content)
+	if err != nil {
+		return message, fmt.Errorf("%w: %v", ErrInvalidCose, err)
+	}
+	var tag cbor.RawTag
+	if err := coseDecoding.Unmarshal(raw, &tag); err != nil || tag.Number != coseSign1Tag {
+		return message, fmt.Errorf("%w: not a tagged COSE_Sign1 structure", ErrInvalidCose)
+	}
+	if err := coseDecoding.Unmarshal(tag.Content, &message); err != nil {
+		return message, fmt.Errorf("%w: %v", ErrInvalidCose, err)
+	}
+	return message, a.setCwtClaims(message.Payload)
+}
+
+// Returns the JWT signing method that matches the algorithm in a protected COSE header.
+func coseSigningMethod(protected []byte) (jwt.SigningMethod, error) {
+	var header map[int64]any
+	if err := coseDecoding.Unmarshal(protected, &header); err != nil {
+		return nil, fmt.Errorf("%w: protected header: %v", ErrInvalidCose, err)
+	}
+	for algorithm, id := range coseAlgorithms {
+		if value, ok := header[coseHeaderAlgorithm].(int64); ok && value == id {
+			if method, ok := entities.SigningMethodOf(algorithm); ok {
+				return method, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: unsupported algorithm %v", ErrInvalidCose, header[coseHeaderAlgorithm])
+}
+
+// Returns the Sig_structure that is signed for a COSE_Sign1 structure, with no external data.
+func sigStructure(protected []byte, payload []byte) ([]byte, error) {
+	return coseEncoding.Marshal([]any{"Signature1", protected, []byte{}, payload})
+}
+
+// Encodes the claims of the assertion as a CBOR Web Token claims set, by way of their JSON form so that
+// every claim is carried under the same name as in a JWT.
+func (a *Assertion) cwtClaims() ([]byte, error) {
+	encoded, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var claims map[string]any
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, err
+	}
+
+	cwt := make(map[any]any, len(claims))
+	for name, value := range claims {
+		value = cborValue(value)
+		key, registered := cwtClaimKeys[name]
+		if !registered {
+			cwt[name] = value
+			continue
+		}
+		switch name {
+		case "aud":
+			if audience, ok := value.([]any); ok && len(audience) == 1 {
+				value = audience[0]
+			}
+		case "jti":
+			value = []byte(fmt.Sprint(value))
+		}
+		cwt[key] = value
+	}
+
+	return coseEncoding.Marshal(cwt)
+}
+
+// Sets the claims of the assertion from a CBOR Web Token claims set encoded by cwtClaims.
+func (a *Assertion) setCwtClaims(payload []byte) error {
+	var cwt map[any]any
+	if err := coseDecoding.Unmarshal(payload, &cwt); err != nil {
+		return fmt.Errorf("%w: claims: %v", ErrInvalidCose, err)
+	}
+
+	claims := make(map[string]any, len(cwt))
+	for key, value := range cwt {
+		name, err := cwtClaimName(key)
+		if err != nil {
+			return err
+		}
+		if id, ok := value.([]byte); ok && name == "jti" {
+			value = string(id)
+		}
+		claims[name], err = jsonValue(value)
+		if err != nil {
+			return fmt.Errorf("%w: claim %s: %v", ErrInvalidCose, name, err)
+		}
+	}
+
+	encoded, err := json.Marshal(claims)
+	if err != nil {
+		return fmt.Errorf("%w: claims: %v", ErrInvalidCose, err)
+	}
+	if err := json.Unmarshal(encoded, a); err != nil {
+		return fmt.Errorf("%w: claims: %v", ErrInvalidCose, err)
+	}
+	return nil
+}
+
+// Returns the JWT name of a claim in a CBOR Web Token. Registered claims must use their integer keys.
+func cwtClaimName(key any) (string, error) {
+	switch k := key.(type) {
+	case string:
+		if _, registered := cwtClaimKeys[k]; !registered {
+			return k, nil
+		}
+	case uint64:
+		for name, number := range cwtClaimKeys {
+			if number == k {
+				return name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: unsupported claim key %v", ErrInvalidCose, key)
+}
+
+// Converts a value decoded from JSON into the equivalent CBOR value, with integers kept as integers.
+func cborValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for n, item := range v {
+			v[n] = cborValue(item)
+		}
+	case map[string]any:
+		for key, item := range v {
+			v[key] = cborValue(item)
+		}
+	}
+	return value
+}
+
+// Converts a value decoded from CBOR into one that can be encoded as JSON.
+func jsonValue(value any) (any, error) {
+	switch v := value.(type) {
+	case []any:
+		for n, item := range v {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[n] = converted
+		}
+	case map[any]any:
+		converted := make(map[string]any, len(v))
+		for key, item := range v {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("unsupported map key %v", key)
+			}
+			convertedItem, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			converted[name] = convertedItem
+		}
+		return converted, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,126 @@
+package assertions
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"silvatek.uk/trustedassertions/internal/entities"
+)
+
+func TestCoseRoundTrip(t *testing.T) {
+	for _, algorithm := range entities.KeyAlgorithms() {
+		privateKey, _ := entities.GenerateKey(algorithm)
+		entity := entities.Entity{CommonName: "Test entity", Issued: time.Now().Add(-time.Hour)}
+		entity.MakeCertificate(privateKey)
+		PublicKeyResolver = TestResolver{entity: entity}
+
+		assertion := NewAssertion(IsTrue)
+		assertion.Subject = "hash://sha256/12345678?type=statement"
+		assertion.SetAssertingEntity(entity)
+		assertion.Confidence = 0.8
+		assertion.Basis = []string{"hash://sha256/9876?type=assertion"}
+		assertion.ID = "assertion-1"
+		assertion.IssuedAt = jwt.NewNumericDate(time.Now().Truncate(time.Second))
+		assertion.ExpiresAt = jwt.NewNumericDate(assertion.IssuedAt.Add(time.Hour))
+
+		jwtForm := assertion
+		jwtForm.MakeJwt(privateKey)
+		assertion.MakeCose(privateKey)
+
+		if GuessContentType(assertion.Content()) != COSE_TYPE || assertion.Format() != CoseFormat {
+			t.Errorf("%s signed COSE assertion not recognised: %s", algorithm, assertion.Content())
+		}
+		if assertion.Uri().Kind() != COSE_KIND {
+			t.Errorf("Unexpected kind of COSE assertion URI: %s", assertion.Uri())
+		}
+		if len(assertion.Content()) >= len(jwtForm.Content()) {
+			t.Errorf("%s COSE assertion is no smaller than the JWT: %d >= %d", algorithm, len(assertion.Content()), len(jwtForm.Content()))
+		}
+
+		parsed, err := ParseAssertionJwt(assertion.Content())
+		if err != nil {
+			t.Fatalf("Error verifying %s signed COSE assertion: %v", algorithm, err)
+		}
+		if parsed.Subject != assertion.Subject || parsed.Issuer != assertion.Issuer || parsed.Confidence != assertion.Confidence ||
+			parsed.Category != assertion.Category || parsed.ID != assertion.ID || len(parsed.Basis) != 1 || parsed.Basis[0] != assertion.Basis[0] ||
+			!parsed.IssuedAt.Equal(assertion.IssuedAt.Time) || !parsed.ExpiresAt.Equal(assertion.ExpiresAt.Time) ||
+			len(parsed.Audience) != 1 || parsed.Audience[0] != DEFAULT_AUDIENCE {
+			t.Errorf("%s COSE claims do not match: %+v %+v", algorithm, parsed, parsed.RegisteredClaims)
+		}
+		if !parsed.Uri().Equals(assertion.Uri()) {
+			t.Errorf("Parsed COSE assertion has a different URI: %s", parsed.Uri())
+		}
+
+		// An assertion signed with a different key must not verify against the entity's certificate
+		otherKey, _ := entities.GenerateKey(algorithm)
+		assertion.MakeCose(otherKey)
+		if _, err := ParseAssertionJwt(assertion.Content()); err == nil {
+			t.Errorf("%s COSE assertion signed with the wrong key was verified", algorithm)
+		}
+	}
+}
+
+func TestCoseTampered(t *testing.T) {
+	privateKey, _ := entities.GenerateKey(entities.Ed25519)
+	entity := entities.NewEntity("Test entity", *big.NewInt(123456))
+	entity.MakeCertificate(privateKey)
+	PublicKeyResolver = TestResolver{entity: entity}
+
+	assertion := NewAssertion(IsTrue)
+	assertion.Subject = "hash://sha256/12345678"
+	assertion.SetAssertingEntity(entity)
+	assertion.MakeCose(privateKey)
+
+	raw, _ := DecodeCose(assertion.Content())
+	tampered := NewAssertion(IsTrue)
+	tampered.Subject = "hash://sha256/87654321"
+	tampered.SetAssertingEntity(entity)
+	payload, _ := tampered.cwtClaims()
+	original, _ := assertion.cwtClaims()
+
+	// Replace the claims without signing them again
+	replaced := make([]byte, 0, len(raw))
+	index := bytes.Index(raw, original)
+	if index < 0 || len(payload) != len(original) {
+		t.Fatalf("Claims not found in COSE structure")
+	}
+	replaced = append(replaced, raw[:index]...)
+	replaced = append(replaced, payload...)
+	replaced = append(replaced, raw[index+len(original):]...)
+
+	if _, err := ParseAssertionJwt(EncodeCose(replaced)); err == nil {
+		t.Error("COSE assertion with altered claims was verified")
+	}
+
+	if _, err := ParseAssertionJwt(EncodeCose([]byte{0xd2, 0x84, 0x40, 0xa0, 0x40, 0x40})); !errors.Is(err, ErrInvalidCose) {
+		t.Errorf("Expected invalid COSE error for a structure without claims: %v", err)
+	}
+}
+
+func TestGuessCoseContentType(t *testing.T) {
+	data := map[string]string{
+		EncodeCose([]byte{0xd2, 0x84, 0x40, 0xa0, 0x40, 0x40}): COSE_TYPE,
+		EncodeCose([]byte{0xd2, 0x84, 0x40, 0xa0, 0x40}):       "Statement", // Truncated
+		EncodeCose([]byte{0xd1, 0x84, 0x40, 0xa0, 0x40, 0x40}): "Statement", // COSE_Mac0
+		"0oRA": "Statement",
+	}
+
+	for input, expected := range data {
+		if output := GuessContentType(input); output != expected {
+			t.Errorf("Unexpected type for `%s`: %s", input, output)
+		}
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	if format, err := FormatOf("cose"); err != nil || format != CoseFormat {
+		t.Errorf("Unexpected format: %s %v", format, err)
+	}
+	if _, err := FormatOf("xml"); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
@@ -2,6 +2,8 @@ package assertions
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"strings"
 
@@ -19,6 +21,8 @@ type Resolver interface {
 	FetchDocument(ctx context.Context, key HashUri) (docs.Document, error)
 	FetchKey(entityUri HashUri) (string, error)
 	FetchRefs(ctx context.Context, key HashUri) ([]Reference, error)
+	FetchRefsOfCategory(ctx context.Context, key HashUri, category string) ([]Reference, error)
+	FetchContent(ctx context.Context, key HashUri) (string, error)
 }
 
 type NullResolver struct{}
@@ -49,13 +53,21 @@ func (r NullResolver) FetchRefs(ctx context.Context, key HashUri) ([]Reference,
 	return []Reference{}, ErrNotImplemented
 }
 
+func (r NullResolver) FetchRefsOfCategory(ctx context.Context, key HashUri, category string) ([]Reference, error) {
+	return []Reference{}, ErrNotImplemented
+}
+
+func (r NullResolver) FetchContent(ctx context.Context, key HashUri) (string, error) {
+	return "", ErrNotImplemented
+}
+
 func NewReferenceable(kind string) Referenceable {
 	switch strings.ToLower(kind) {
 	case "statement":
 		return &statements.Statement{}
 	case "entity":
 		return &entities.Entity{}
-	case "assertion":
+	case "assertion", COSE_KIND:
 		return &Assertion{}
 	case "document":
 		return &docs.Document{}
@@ -64,21 +76,60 @@ func NewReferenceable(kind string) Referenceable {
 	}
 }
 
+// Returns the URI with the kind of the stored item that it refers to, if the URI does not include it.
+// The kind is guessed from the stored content, and URIs of items that are not stored are taken to be statements.
+func TypedUri(ctx context.Context, resolver Resolver, uri HashUri) HashUri {
+	if uri.HasType() {
+		return uri
+	}
+	content, err := resolver.FetchContent(ctx, uri)
+	if err != nil {
+		return uri.WithType("statement")
+	}
+	return uri.WithType(GuessContentType(content))
+}
+
+// Guesses the type of a stored item from its content.
+//
+// Certificates, JWTs and COSE assertions are recognised by their structure rather than their length,
+// since ECDSA and Ed25519 keys produce certificates and JWTs that are much shorter than RSA ones.
 func GuessContentType(content string) string {
 	if strings.HasPrefix(content, "<?xml") && strings.Contains(content, "<document>") {
 		return "Document"
 	}
-	if len(content) < 512 {
-		// Both X509 certificates and JWTs signed by Entities are longer than 512 characters
-		return "Statement"
-	}
 	if strings.HasPrefix(content, "-----BEGIN CERTIFICATE----") {
 		// X509 certificates for Entities are self-describing
 		return "Entity"
 	}
-	if strings.HasPrefix(content, "eyJ") {
-		// Assertion JWTs start with bas64-encoded "{"
+	if isJwt(content) {
 		return "Assertion"
 	}
+	if IsCose(content) {
+		return COSE_TYPE
+	}
 	return "Statement"
 }
+
+// Whether the content is a compact JWT: three base64url-encoded parts, the first of which is a JSON header
+// naming the signing algorithm.
+func isJwt(content string) bool {
+	parts := strings.Split(content, ".")
+	if len(parts) != 3 || !strings.HasPrefix(content, "eyJ") {
+		return false
+	}
+	for _, part := range parts[1:] {
+		if _, err := base64.RawURLEncoding.DecodeString(part); err != nil || part == "" {
+			return false
+		}
+	}
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return false
+	}
+	var fields map[string]any
+	if json.Unmarshal(header, &fields) != nil {
+		return false
+	}
+	_, found := fields["alg"]
+	return found
+}
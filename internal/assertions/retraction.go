@@ -0,0 +1,17 @@
+package assertions
+
+import (
+	"context"
+)
+
+// Finds the Retracts assertion by which the issuer of an assertion has withdrawn it.
+//
+// Only the entity that issued an assertion can retract it, so retractions by anyone else are
+// ignored, as are retractions that are not in force.
+func RetractionOf(ctx context.Context, resolver Resolver, assertion Assertion) (Assertion, bool) {
+	retraction := AnnotationsOf(ctx, resolver, assertion).Retraction
+	if retraction == nil {
+		return Assertion{}, false
+	}
+	return *retraction, true
+}
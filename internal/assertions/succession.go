@@ -0,0 +1,140 @@
+package assertions
+
+import (
+	"context"
+
+	"silvatek.uk/trustedassertions/internal/entities"
+	"silvatek.uk/trustedassertions/internal/references"
+)
+
+// Succession links two certificates of the same identity, where the successor has replaced the
+// original by renewing its certificate or by rotating to a new key.
+//
+// A renewal is a new certificate for the same key, recorded by a Replaces assertion from the successor
+// about the original. A key rotation is recorded by a Replaces assertion from the original, signed
+// with the old key, whose basis is the same assertion from the successor, signed with the new key.
+// Either way, nobody but the holder of the original key can link another entity to it.
+type Succession struct {
+	Original  entities.Entity
+	Successor entities.Entity
+	Assertion references.HashUri // The Replaces assertion, by the successor for renewals and by the original for rotations
+	Rotated   bool               // Whether the successor has a new key
+}
+
+// Finds the entity that replaced an entity, by renewal or key rotation.
+func SuccessorOf(ctx context.Context, resolver Resolver, entity entities.Entity) (Succession, bool) {
+	return findSuccession(ctx, resolver, entity, func(a Assertion) bool {
+		return references.UriFromString(a.Object).Hash() == entity.Uri().Hash()
+	})
+}
+
+// Finds the entity that an entity replaced, by renewal or key rotation.
+func PredecessorOf(ctx context.Context, resolver Resolver, entity entities.Entity) (Succession, bool) {
+	return findSuccession(ctx, resolver, entity, func(a Assertion) bool {
+		return references.UriFromString(a.Subject).Hash() == entity.Uri().Hash()
+	})
+}
+
+// Returns the entities with the same identity as an entity, from the successions in either direction.
+func SuccessionsOf(ctx context.Context, resolver Resolver, entity entities.Entity) []Succession {
+	successions := make([]Succession, 0, 2)
+	if succession, found := PredecessorOf(ctx, resolver, entity); found {
+		successions = append(successions, succession)
+	}
+	if succession, found := SuccessorOf(ctx, resolver, entity); found {
+		successions = append(successions, succession)
+	}
+	return successions
+}
+
+// Returns the URI of the other entity in a succession involving the specified entity.
+func (s Succession) Other(entity references.HashUri) references.HashUri {
+	if s.Original.Uri().Hash() == entity.Hash() {
+		return s.Successor.Uri()
+	}
+	return s.Original.Uri()
+}
+
+// Finds a Replaces assertion referring to the entity that matches the filter and links two
+// certificates of the same identity.
+func findSuccession(ctx context.Context, resolver Resolver, entity entities.Entity, filter func(Assertion) bool) (Succession, bool) {
+	refs, err := resolver.FetchRefs(ctx, entity.Uri())
+	if err != nil {
+		return Succession{}, false
+	}
+
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if !IsAssertionKind(ref.Source.Kind()) || seen[ref.Source.Escaped()] {
+			continue
+		}
+		seen[ref.Source.Escaped()] = true
+
+		assertion, err := resolver.FetchAssertion(ctx, ref.Source)
+		if err != nil || !filter(assertion) {
+			continue
+		}
+		if succession, found := successionOf(ctx, resolver, assertion); found {
+			return succession, true
+		}
+	}
+
+	return Succession{}, false
+}
+
+// Returns the succession recorded by an assertion, if it is a renewal by the successor or a key
+// rotation by the original.
+func successionOf(ctx context.Context, resolver Resolver, assertion Assertion) (Succession, bool) {
+	if !isReplacement(assertion) {
+		return Succession{}, false
+	}
+	original, err := resolver.FetchEntity(ctx, entityUri(assertion.Object))
+	if err != nil {
+		return Succession{}, false
+	}
+	successor, err := resolver.FetchEntity(ctx, entityUri(assertion.Subject))
+	if err != nil {
+		return Succession{}, false
+	}
+	succession := Succession{Original: original, Successor: successor, Assertion: assertion.Uri()}
+
+	issuer := references.UriFromString(assertion.Issuer).Hash()
+	switch {
+	case issuer == successor.Uri().Hash():
+		return succession, successor.HasSameKey(original)
+	case issuer == original.Uri().Hash():
+		succession.Rotated = true
+		return succession, !successor.HasSameKey(original) && confirmedBySuccessor(ctx, resolver, assertion)
+	default:
+		return Succession{}, false
+	}
+}
+
+// Whether the basis of a key rotation includes the same replacement, issued by the successor.
+func confirmedBySuccessor(ctx context.Context, resolver Resolver, rotation Assertion) bool {
+	for _, basis := range rotation.Basis {
+		confirmation, err := resolver.FetchAssertion(ctx, references.MakeUri(references.UriFromString(basis).Hash(), "assertion"))
+		if err != nil || !isReplacement(confirmation) {
+			continue
+		}
+		if sameEntity(confirmation.Subject, rotation.Subject) && sameEntity(confirmation.Object, rotation.Object) &&
+			sameEntity(confirmation.Issuer, rotation.Subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether an assertion is an active Replaces assertion about one entity replacing another.
+func isReplacement(assertion Assertion) bool {
+	return AssertionTypeOf(assertion.Category) == Replaces && assertion.IsActive() &&
+		assertion.Object != "" && !sameEntity(assertion.Subject, assertion.Object)
+}
+
+func sameEntity(a string, b string) bool {
+	return references.UriFromString(a).Hash() == references.UriFromString(b).Hash()
+}
+
+func entityUri(uri string) references.HashUri {
+	return references.MakeUri(references.UriFromString(uri).Hash(), "entity")
+}
@@ -0,0 +1,82 @@
+package assertions
+
+import (
+	"context"
+	"time"
+
+	"silvatek.uk/trustedassertions/internal/references"
+)
+
+// The issue time of an assertion is whatever its signer claims, so it can be backdated. The server
+// can act as a timestamp authority, in the manner of RFC 3161, by countersigning each assertion it
+// receives with an IsTimestamped assertion issued by its own entity. The subject of the timestamp
+// is the URI of the assertion, whose hash stands in for the message imprint, and the issue time of
+// the timestamp is when the assertion was received. The assertion must therefore have been signed
+// no later than that time, whatever it claims.
+
+// The entity that acts as the timestamp authority, or an empty URI if assertions are not timestamped.
+var TimestampAuthority references.HashUri
+
+// Makes an entity the timestamp authority, returning a function that restores the previous authority.
+func UseTimestampAuthority(authority references.HashUri) func() {
+	previous := TimestampAuthority
+	TimestampAuthority = authority
+	return func() { TimestampAuthority = previous }
+}
+
+// Whether an assertion is a timestamp issued by the timestamp authority.
+func IsTimestamp(assertion Assertion) bool {
+	return !TimestampAuthority.IsEmpty() &&
+		AssertionTypeOf(assertion.Category) == IsTimestamped &&
+		references.UriFromString(assertion.Issuer).Hash() == TimestampAuthority.Hash()
+}
+
+// Finds the earliest timestamp that the timestamp authority has issued for an assertion.
+//
+// Timestamps issued by any other entity are ignored, as are those without an issue time.
+func TimestampOf(ctx context.Context, resolver Resolver, assertion Assertion) (Assertion, bool) {
+	timestamp := AnnotationsOf(ctx, resolver, assertion).Timestamp
+	if timestamp == nil {
+		return Assertion{}, false
+	}
+	return *timestamp, true
+}
+
+// Looks up the time at which the timestamp authority received the assertion, so that it is used in
+// place of the claimed issue time. Returns the timestamp, if the assertion has been timestamped.
+func (a *Assertion) ApplyTimestamp(ctx context.Context, resolver Resolver) (Assertion, bool) {
+	timestamp, found := TimestampOf(ctx, resolver, *a)
+	if found {
+		a.timestamp = timestamp.IssuedAt.Time
+	}
+	return timestamp, found
+}
+
+// Returns the time at which the timestamp authority received the assertion, or the zero time if
+// it has not been timestamped or the timestamp has not been applied.
+func (a Assertion) TimestampedAt() time.Time {
+	return a.timestamp
+}
+
+// Returns the time at which the assertion is taken to have been issued. This is the trusted time from
+// its timestamp if one has been applied, and otherwise the issue time claimed by its signer. Returns
+// the zero time if neither is known.
+func (a Assertion) IssueTime() time.Time {
+	if !a.timestamp.IsZero() {
+		return a.timestamp
+	}
+	if a.RegisteredClaims != nil && a.IssuedAt != nil {
+		return a.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
+// Returns how long before it was received by the timestamp authority the assertion claims to have been
+// issued, or zero if it has not been timestamped or claims no earlier time. A long delay is a sign that
+// the assertion has been backdated, although it could also have been signed offline.
+func (a Assertion) TimestampDelay() time.Duration {
+	if a.timestamp.IsZero() || a.RegisteredClaims == nil || a.IssuedAt == nil || !a.IssuedAt.Before(a.timestamp) {
+		return 0
+	}
+	return a.timestamp.Sub(a.IssuedAt.Time)
+}
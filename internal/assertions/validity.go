@@ -0,0 +1,53 @@
+package assertions
+
+import "time"
+
+// Validity is whether an assertion is in force at a particular time, according to its
+// not-before and expiry times.
+type Validity string
+
+const (
+	Active      Validity = "Active"
+	Expired     Validity = "Expired"
+	NotYetValid Validity = "NotYetValid"
+)
+
+// Returns the validity of the assertion at the specified time.
+func (a Assertion) ValidityAt(t time.Time) Validity {
+	if a.RegisteredClaims == nil {
+		return Active
+	}
+	if a.NotBefore != nil && t.Before(a.NotBefore.Time) {
+		return NotYetValid
+	}
+	if a.ExpiresAt != nil && !t.Before(a.ExpiresAt.Time) {
+		return Expired
+	}
+	return Active
+}
+
+// Returns the validity of the assertion now.
+func (a Assertion) Validity() Validity {
+	return a.ValidityAt(time.Now())
+}
+
+// Whether the assertion is in force now, so that it should be used by trust models.
+func (a Assertion) IsActive() bool {
+	return a.Validity() == Active
+}
+
+// Returns a description of the validity of the assertion now, for display.
+func (a Assertion) ValidityDescription() string {
+	const layout = "2 Jan 2006 15:04"
+	switch a.Validity() {
+	case Expired:
+		return "Expired on " + a.ExpiresAt.Time.Format(layout)
+	case NotYetValid:
+		return "Not valid until " + a.NotBefore.Time.Format(layout)
+	default:
+		if a.RegisteredClaims != nil && a.ExpiresAt != nil {
+			return "Active until " + a.ExpiresAt.Time.Format(layout)
+		}
+		return "Active"
+	}
+}
@@ -2,13 +2,18 @@ package auth
 
 import (
 	"crypto/rand"
+	"net/http"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	log "silvatek.uk/trustedassertions/internal/logging"
 )
 
 const ISSUER = "trustedassertions"
 
+// The key that signs the JWTs in the auth cookies of logged-in users, shared by the web pages and the API.
+var UserJwtKey = MakeJwtKey()
+
 // Makes a shared key for signing user JWTs for our own consumption
 func MakeJwtKey() []byte {
 	key := make([]byte, 10)
@@ -49,3 +54,17 @@ func ParseUserJwt(token string, jwtKey []byte) (string, error) {
 
 	return userToken.Claims.GetSubject()
 }
+
+// Returns the name of the user logged in with the request's auth cookie, or an empty string.
+func RequestUsername(r *http.Request) string {
+	cookie, err := r.Cookie("auth")
+	if err != nil || cookie.Value == "" {
+		return ""
+	}
+	userName, err := ParseUserJwt(cookie.Value, UserJwtKey)
+	if err != nil {
+		log.Errorf("Error parsing user JWT: %v", err)
+		return ""
+	}
+	return userName
+}
@@ -8,9 +8,10 @@ import (
 )
 
 type User struct {
-	Id       string `json:"id"`
-	PassHash string `json:"passhash"`
-	KeyRefs  []KeyRef
+	Id         string `json:"id"`
+	PassHash   string `json:"passhash"`
+	KeyRefs    []KeyRef
+	TrustRoots []TrustRoot
 }
 
 type KeyRef struct {
@@ -19,6 +20,12 @@ type KeyRef struct {
 	Summary string `json:"summary"`
 }
 
+// TrustRoot is an entity that a user trusts, with the weight of that trust (0.0 to 1.0).
+type TrustRoot struct {
+	Entity string  `json:"entity"`
+	Weight float64 `json:"weight"`
+}
+
 func (u *User) AddKeyRef(keyId string, summary string) {
 	if u.KeyRefs == nil {
 		u.KeyRefs = make([]KeyRef, 0)
@@ -26,6 +33,18 @@ func (u *User) AddKeyRef(keyId string, summary string) {
 	u.KeyRefs = append(u.KeyRefs, KeyRef{UserId: u.Id, KeyId: keyId, Summary: summary})
 }
 
+// Points the user's reference to one key at another key instead, such as after a key rotation,
+// returning false if the user has no reference to the original key.
+func (u *User) ReplaceKeyRef(keyId string, newKeyId string, summary string) bool {
+	for n, k := range u.KeyRefs {
+		if k.KeyId == keyId {
+			u.KeyRefs[n] = KeyRef{UserId: u.Id, KeyId: newKeyId, Summary: summary}
+			return true
+		}
+	}
+	return false
+}
+
 var DefaultHashCost int = bcrypt.DefaultCost
 
 func (u *User) HashPassword(plaintext string) {
@@ -63,3 +82,24 @@ func (u *User) HasKey(keyId string) bool {
 
 	return false
 }
+
+// Adds an entity to the user's trust roots, or updates its weight if it is already trusted.
+func (u *User) AddTrustRoot(entity string, weight float64) {
+	for n, root := range u.TrustRoots {
+		if root.Entity == entity {
+			u.TrustRoots[n].Weight = weight
+			return
+		}
+	}
+	u.TrustRoots = append(u.TrustRoots, TrustRoot{Entity: entity, Weight: weight})
+}
+
+func (u *User) RemoveTrustRoot(entity string) {
+	roots := make([]TrustRoot, 0)
+	for _, root := range u.TrustRoots {
+		if root.Entity != entity {
+			roots = append(roots, root)
+		}
+	}
+	u.TrustRoots = roots
+}
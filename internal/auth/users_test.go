@@ -90,6 +90,14 @@ func TestKeyRefs(t *testing.T) {
 	if user.HasKey("xyz") {
 		t.Error("Expected user to not have different key after addition")
 	}
+
+	if !user.ReplaceKeyRef("abc", "def", "c") || user.HasKey("abc") || !user.HasKey("def") || len(user.KeyRefs) != 1 {
+		t.Error("Expected key reference to be replaced")
+	}
+
+	if user.ReplaceKeyRef("xyz", "ghi", "d") {
+		t.Error("Expected no replacement of a key the user does not have")
+	}
 }
 
 func TestParseBadJwt(t *testing.T) {
@@ -102,3 +110,23 @@ func TestParseBadJwt(t *testing.T) {
 		t.Error("Parsing broken JWT did not return an empty string")
 	}
 }
+
+func TestTrustRoots(t *testing.T) {
+	user := User{Id: "x"}
+
+	user.AddTrustRoot("abc", 1.0)
+	user.AddTrustRoot("def", 0.5)
+	user.AddTrustRoot("abc", 0.8)
+
+	if len(user.TrustRoots) != 2 {
+		t.Errorf("Unexpected number of trust roots: %d", len(user.TrustRoots))
+	}
+	if user.TrustRoots[0].Weight != 0.8 {
+		t.Errorf("Trust root weight not updated: %f", user.TrustRoots[0].Weight)
+	}
+
+	user.RemoveTrustRoot("abc")
+	if len(user.TrustRoots) != 1 || user.TrustRoots[0].Entity != "def" {
+		t.Errorf("Unexpected trust roots after removal: %v", user.TrustRoots)
+	}
+}
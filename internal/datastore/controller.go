@@ -2,8 +2,10 @@ package datastore
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
+	"crypto"
+	"errors"
+	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -18,25 +20,230 @@ import (
 
 var ActiveDataStore DataStore
 
-func CreateAssertion(ctx context.Context, statementUri references.HashUri, entityUri references.HashUri, kind assertions.AssertionType, confidence float64, privateKey *rsa.PrivateKey) *assertions.Assertion {
+// Returned when creating an assertion whose basis includes something other than a verified assertion.
+var ErrInvalidBasis = errors.New("basis is not a verified assertion")
+
+// Returned when creating an assertion that would expire before it becomes valid.
+var ErrInvalidValidity = errors.New("assertion expires before it is valid")
+
+// Returned when creating a retraction of something other than an assertion by the same issuer.
+var ErrInvalidRetraction = errors.New("only the issuer of an assertion can retract it")
+
+// Returned when creating an assertion whose category is not registered, or does not allow its subject or object.
+var ErrInvalidCategory = errors.New("assertion category not valid")
+
+// Returned when creating a timestamp issued by anyone other than the timestamp authority.
+var ErrInvalidTimestamp = errors.New("only the timestamp authority can issue timestamps")
+
+// Returned when creating a document from content that is not valid document XML, or whose spans
+// make assertions that are not valid.
+var ErrInvalidDocument = errors.New("document not valid")
+
+// Returned when creating a document that names an author other than the entity signing it.
+var ErrDocumentAuthor = errors.New("document author is not the signing entity")
+
+// Returned when storing a pre-signed assertion whose signature or issue time cannot be verified.
+var ErrInvalidSubmission = errors.New("signed assertion not valid")
+
+// Creates an assertion about the subject, citing any basis assertions that support it, then signs and stores it.
+func CreateAssertion(ctx context.Context, subjectUri references.HashUri, entityUri references.HashUri, kind assertions.AssertionType, confidence float64, privateKey crypto.Signer, basis ...references.HashUri) (*assertions.Assertion, error) {
 	assertion := assertions.NewAssertion(kind)
-	assertion.Subject = statementUri.String()
-	assertion.IssuedAt = jwt.NewNumericDate(time.Now())
-	assertion.NotBefore = assertion.IssuedAt
+	assertion.Subject = subjectUri.String()
 	assertion.Confidence = float32(confidence)
 	assertion.Issuer = entityUri.String()
+	for _, uri := range basis {
+		assertion.Basis = append(assertion.Basis, uri.String())
+	}
+
+	return CreateSignedAssertion(ctx, assertion, privateKey)
+}
+
+// Sets the issue time of an assertion whose other claims have already been populated, then signs it
+// with the private key and stores it, along with references to everything that it refers to.
+//
+// The category must be registered and allow the kinds of the subject and any object of the assertion.
+// Each URI in the basis of the assertion must be that of a stored assertion whose signature can be verified.
+// The assertion is valid from the time it is issued unless it already has a not-before time, and any expiry
+// time must come after that. A retraction must be of a stored assertion made by the same issuer, and the
+// issuer must be a stored entity whose certificate is valid when the assertion is issued. Only an IsCompromised
+// assertion can be signed with a key that its entity has declared compromised by then. The stored assertion is timestamped by
+// the timestamp authority, if there is one.
+func CreateSignedAssertion(ctx context.Context, assertion assertions.Assertion, privateKey crypto.Signer) (*assertions.Assertion, error) {
+	return CreateSignedAssertionAs(ctx, assertion, privateKey, assertions.JwtFormat)
+}
+
+// Creates an assertion in the same way as CreateSignedAssertion, signing it in the specified format.
+func CreateSignedAssertionAs(ctx context.Context, assertion assertions.Assertion, privateKey crypto.Signer, format assertions.Format) (*assertions.Assertion, error) {
+	assertion.IssuedAt = jwt.NewNumericDate(time.Now())
+	issuer, err := ActiveDataStore.FetchEntity(ctx, references.UriFromString(assertion.Issuer))
+	if err != nil {
+		return nil, err
+	}
+	if err := issuer.CheckValidAt(assertion.IssuedAt.Time); err != nil {
+		return nil, err
+	}
+	if assertions.AssertionTypeOf(assertion.Category) != assertions.IsCompromised {
+		if compromise, found := assertions.SelfCompromise(ctx, ActiveDataStore, issuer.Uri()); found && compromise.Covers(assertion) {
+			return nil, assertions.ErrCompromised
+		}
+	}
+	if assertion.NotBefore == nil {
+		assertion.NotBefore = assertion.IssuedAt
+	}
+	if err := checkClaims(ctx, &assertion); err != nil {
+		return nil, err
+	}
+
 	assertion.SetSummary(assertions.SummariseAssertion(ctx, assertion, nil, ActiveDataStore))
-	assertion.MakeJwt(privateKey)
+	assertion.Sign(privateKey, format)
 	ActiveDataStore.Store(ctx, &assertion)
 
 	CreateReferences(ctx, &assertion)
+	timestampReceived(ctx, &assertion)
 
-	return &assertion
+	return &assertion, nil
 }
 
-func CreateReferences(ctx context.Context, target references.Referenceable) {
-	for _, uri := range target.References() {
-		CreateReferenceWithSummary(ctx, uri, target.Uri())
+// How far in the future the issue time of a pre-signed assertion can be, to allow for clock differences.
+const submissionClockSkew = 5 * time.Minute
+
+// Verifies an assertion that has already been signed with the issuer's own private key, then stores it
+// along with references to everything that it refers to, so that the key never has to reach the server.
+//
+// The signature is verified against the certificate of the issuer, which must be a stored entity that was
+// valid when the assertion was issued, so the assertion must have an issue time. The category, basis, validity
+// period and any retraction are checked as they are by CreateSignedAssertion, but the claims are stored exactly
+// as they were signed. Submitting an assertion that is already stored returns the stored assertion.
+//
+// New assertions are timestamped by the timestamp authority, if there is one. Assertions from an issuer
+// that has declared its key compromised are then rejected, however early they claim to have been issued.
+func StoreSignedAssertion(ctx context.Context, content string) (*assertions.Assertion, error) {
+	assertion, err := assertions.ParseAssertionJwt(strings.TrimSpace(content))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSubmission, err)
+	}
+	if assertion.IssuedAt == nil {
+		return nil, fmt.Errorf("%w: no issue time", ErrInvalidSubmission)
+	}
+	if assertion.IssuedAt.After(time.Now().Add(submissionClockSkew)) {
+		return nil, fmt.Errorf("%w: issued in the future at %v", ErrInvalidSubmission, assertion.IssuedAt.Time)
+	}
+
+	if existing, err := ActiveDataStore.FetchAssertion(ctx, assertion.Uri()); err == nil {
+		return &existing, nil
+	}
+
+	// Once timestamped, the assertion will be taken to have been issued now rather than when it claims
+	if !assertions.TimestampAuthority.IsEmpty() && assertions.AssertionTypeOf(assertion.Category) != assertions.IsCompromised {
+		issuer := references.UriFromString(assertion.Issuer)
+		if compromise, found := assertions.SelfCompromise(ctx, ActiveDataStore, issuer); found && !compromise.CompromisedSince().After(time.Now()) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidSubmission, assertions.ErrCompromised)
+		}
+	}
+
+	// The checks normalise URIs, which must not change the claims that were signed
+	claims := assertion
+	registered := *assertion.RegisteredClaims
+	claims.RegisteredClaims = &registered
+	claims.Basis = slices.Clone(assertion.Basis)
+	if claims.NotBefore == nil {
+		claims.NotBefore = claims.IssuedAt
+	}
+	if err := checkClaims(ctx, &claims); err != nil {
+		return nil, err
+	}
+
+	assertion.SetSummary(assertions.SummariseAssertion(ctx, assertion, nil, ActiveDataStore))
+	ActiveDataStore.Store(ctx, &assertion)
+
+	CreateReferences(ctx, &assertion)
+	timestampReceived(ctx, &assertion)
+
+	return &assertion, nil
+}
+
+// Checks the category, retraction, timestamp, basis and validity period of an assertion before it is stored,
+// normalising the URIs of the retracted assertion and the basis to typed URIs.
+func checkClaims(ctx context.Context, assertion *assertions.Assertion) error {
+	if assertions.AssertionTypeOf(assertion.Category) == assertions.Retracts {
+		if err := checkRetraction(ctx, assertion); err != nil {
+			return err
+		}
+	}
+	if assertions.AssertionTypeOf(assertion.Category) == assertions.IsTimestamped && !assertions.IsTimestamp(*assertion) {
+		return fmt.Errorf("%w: issued by %s", ErrInvalidTimestamp, assertion.Issuer)
+	}
+	if err := checkCategory(ctx, assertion); err != nil {
+		return err
+	}
+
+	for n, basis := range assertion.Basis {
+		uri := references.UriFromString(basis)
+		if !uri.HasType() {
+			uri = uri.WithType("assertion")
+		}
+		if !assertions.IsAssertionKind(uri.Kind()) {
+			return fmt.Errorf("%w: %s is not an assertion", ErrInvalidBasis, basis)
+		}
+		supporting, err := ActiveDataStore.FetchAssertion(ctx, uri)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidBasis, basis, err)
+		}
+		assertion.Basis[n] = supporting.Uri().String()
+	}
+
+	if assertion.ExpiresAt != nil && !assertion.ExpiresAt.After(assertion.NotBefore.Time) {
+		return fmt.Errorf("%w: expires at %v, valid from %v", ErrInvalidValidity, assertion.ExpiresAt.Time, assertion.NotBefore.Time)
+	}
+
+	return nil
+}
+
+// Checks that the category of an assertion is registered and allows the kinds of its subject and object,
+// which are found from the stored content when their URIs do not include them.
+func checkCategory(ctx context.Context, assertion *assertions.Assertion) error {
+	category, found := assertions.CategoryOf(assertion.Category)
+	if !found {
+		return fmt.Errorf("%w: %s is not a registered category", ErrInvalidCategory, assertion.Category)
+	}
+	subjectKind := assertions.TypedUri(ctx, ActiveDataStore, references.UriFromString(assertion.Subject)).Kind()
+	if !category.AllowsSubject(subjectKind) {
+		return fmt.Errorf("%w: %s cannot be about a %s", ErrInvalidCategory, category.Name, subjectKind)
+	}
+	objectKind := ""
+	if assertion.Object != "" {
+		objectKind = assertions.TypedUri(ctx, ActiveDataStore, references.UriFromString(assertion.Object)).Kind()
+	}
+	if !category.AllowsObject(subjectKind, objectKind) {
+		return fmt.Errorf("%w: %s about a %s cannot have an object of kind %q", ErrInvalidCategory, category.Name, subjectKind, objectKind)
+	}
+	return nil
+}
+
+// Checks that the subject of a retraction is an assertion made by the same issuer, normalising it to a typed URI.
+func checkRetraction(ctx context.Context, retraction *assertions.Assertion) error {
+	uri := references.UriFromString(retraction.Subject)
+	if !uri.HasType() {
+		uri = uri.WithType("assertion")
+	}
+	if !assertions.IsAssertionKind(uri.Kind()) {
+		return fmt.Errorf("%w: %s is not an assertion", ErrInvalidRetraction, retraction.Subject)
+	}
+	original, err := ActiveDataStore.FetchAssertion(ctx, uri)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidRetraction, uri, err)
+	}
+	if references.UriFromString(original.Issuer).Hash() != references.UriFromString(retraction.Issuer).Hash() {
+		return fmt.Errorf("%w: %s was issued by %s", ErrInvalidRetraction, uri, original.Issuer)
+	}
+	retraction.Subject = original.Uri().String()
+	return nil
+}
+
+// Creates a reference from the source to each of the URIs that it refers to.
+func CreateReferences(ctx context.Context, source references.Referenceable) {
+	for _, uri := range source.References() {
+		CreateReferenceWithSummary(ctx, source.Uri(), uri)
 	}
 }
 
@@ -53,11 +260,10 @@ func CreateReferenceWithSummary(ctx context.Context, source references.HashUri,
 func CreateStatementAndAssertion(ctx context.Context, content string, entityUri references.HashUri, kind assertions.AssertionType, confidence float64) (*assertions.Assertion, error) {
 	log.DebugfX(ctx, "Creating statement and assertion")
 
-	b64key, err := ActiveDataStore.FetchKey(entityUri)
+	privateKey, err := FetchPrivateKey(entityUri)
 	if err != nil {
 		return nil, err
 	}
-	privateKey := entities.PrivateKeyFromString(b64key)
 	entity, err := ActiveDataStore.FetchEntity(ctx, entityUri)
 	if err != nil {
 		return nil, err
@@ -69,17 +275,20 @@ func CreateStatementAndAssertion(ctx context.Context, content string, entityUri
 
 	log.DebugfX(ctx, "Statement created")
 
-	// Create and save an assertion by the default entity that the statement is probably true
-	assertion := CreateAssertion(ctx, statement.Uri(), entity.Uri(), "IsTrue", confidence, privateKey)
+	// Create and save an assertion of the specified kind about the statement
+	assertion, err := CreateAssertion(ctx, statement.Uri(), entity.Uri(), kind, confidence, privateKey)
+	if err != nil {
+		return nil, err
+	}
 
 	log.DebugfX(ctx, "Assertion created")
 
 	return assertion, nil
 }
 
-// Populates the summary field of a Reference based on the source of the reference.
+// Populates the summary field of a Reference based on the source of the reference, and its category if the source is an assertion.
 func MakeReferenceSummary(ctx context.Context, target *references.Referenceable, ref *references.Reference, resolver assertions.Resolver) {
-	switch ref.Source.Kind() {
+	switch assertions.TypedUri(ctx, resolver, ref.Source).Kind() {
 	case "statement":
 		statement, _ := resolver.FetchStatement(ctx, ref.Source)
 		ref.Summary = statement.Summary()
@@ -89,7 +298,7 @@ func MakeReferenceSummary(ctx context.Context, target *references.Referenceable,
 	case "document":
 		doc, _ := resolver.FetchDocument(ctx, ref.Source)
 		ref.Summary = doc.Summary()
-	case "assertion":
+	case "assertion", assertions.COSE_KIND:
 		var assertion assertions.Assertion
 		if target != nil && (*target).Uri().Equals(ref.Source) {
 			assertion = *((*target).(*assertions.Assertion))
@@ -102,6 +311,14 @@ func MakeReferenceSummary(ctx context.Context, target *references.Referenceable,
 		}
 		summary := assertions.SummariseAssertion(ctx, assertion, cache, resolver)
 		ref.Summary = summary
+		ref.Category = assertion.Category
+		if ref.Category == "" {
+			// Assertions that cannot be verified are still indexed by category, as their users verify them
+			if content, err := resolver.FetchContent(ctx, ref.Source); err == nil {
+				claims, _ := assertions.ParseUnverified(content)
+				ref.Category = claims.Category
+			}
+		}
 	default:
 		ref.Summary = "Unknown " + ref.Source.Kind()
 	}
@@ -116,50 +333,226 @@ func CreateStatement(ctx context.Context, content string) references.HashUri {
 
 // Creates a new Entity with a private key and stores both in the active
 func CreateEntityWithKey(ctx context.Context, commonName string) references.HashUri {
-	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	uri, _ := CreateEntityWithAlgorithm(ctx, commonName, entities.DefaultKeyAlgorithm)
+	return uri
+}
+
+// Creates a new Entity with a private key of the specified algorithm and stores both in the active datastore.
+func CreateEntityWithAlgorithm(ctx context.Context, commonName string, algorithm entities.KeyAlgorithm) (references.HashUri, error) {
+	privateKey, err := entities.GenerateKey(algorithm)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
 	entity := entities.Entity{CommonName: commonName}
 	entity.MakeCertificate(privateKey)
 
 	ActiveDataStore.Store(ctx, &entity)
 
-	ActiveDataStore.StoreKey(entity.Uri(), entities.PrivateKeyToString(privateKey))
+	if err := StorePrivateKey(entity.Uri(), privateKey); err != nil {
+		return references.ERROR_URI, err
+	}
+
+	return entity.Uri(), nil
+}
+
+// Creates a new Entity as a member of an organisation, with a certificate issued using the organisation's
+// private key, and stores the entity, its own private key and its reference to the organisation.
+func CreateMemberEntity(ctx context.Context, commonName string, algorithm entities.KeyAlgorithm, organisationUri references.HashUri) (references.HashUri, error) {
+	organisation, err := ActiveDataStore.FetchEntity(ctx, organisationUri)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+	organisationKey, err := FetchPrivateKey(organisationUri)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+	privateKey, err := entities.GenerateKey(algorithm)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+
+	entity := entities.Entity{CommonName: commonName}
+	if err := entity.MakeMemberCertificate(privateKey, organisation, organisationKey); err != nil {
+		return references.ERROR_URI, err
+	}
+
+	ActiveDataStore.Store(ctx, &entity)
+	if err := StorePrivateKey(entity.Uri(), privateKey); err != nil {
+		return references.ERROR_URI, err
+	}
+	CreateReferences(ctx, &entity)
+
+	return entity.Uri(), nil
+}
+
+// Renews an entity by issuing a new certificate for the same private key, either self-signed or by the
+// organisation that issued the original. The new entity is stored along with an assertion, signed with
+// the shared key, that it replaces the original.
+func RenewEntity(ctx context.Context, entityUri references.HashUri) (references.HashUri, error) {
+	entity, err := ActiveDataStore.FetchEntity(ctx, entityUri)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+	privateKey, err := FetchPrivateKey(entityUri)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+	parent, parentKey, err := fetchIssuer(ctx, entity)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+
+	renewed, err := entity.Renew(privateKey, parent, parentKey)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+	ActiveDataStore.Store(ctx, &renewed)
+	if err := StorePrivateKey(renewed.Uri(), privateKey); err != nil {
+		return references.ERROR_URI, err
+	}
+	CreateReferences(ctx, &renewed)
+
+	if _, err := createReplacement(ctx, renewed.Uri(), entity.Uri(), renewed.Uri(), privateKey); err != nil {
+		return references.ERROR_URI, err
+	}
+
+	return renewed.Uri(), nil
+}
+
+// Rotates the key of an entity by making a new entity with a new private key of the specified algorithm,
+// issued in the same way as the original. The new entity is linked to the original by an assertion that
+// it replaces the original, signed with the new key, which is cited as the basis of the same assertion
+// signed with the original key.
+func RotateEntity(ctx context.Context, entityUri references.HashUri, algorithm entities.KeyAlgorithm) (references.HashUri, error) {
+	entity, err := ActiveDataStore.FetchEntity(ctx, entityUri)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+	oldKey, err := FetchPrivateKey(entityUri)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+	parent, parentKey, err := fetchIssuer(ctx, entity)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+	newKey, err := entities.GenerateKey(algorithm)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+
+	rotated, err := entity.Rotate(newKey, parent, parentKey)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+	ActiveDataStore.Store(ctx, &rotated)
+	if err := StorePrivateKey(rotated.Uri(), newKey); err != nil {
+		return references.ERROR_URI, err
+	}
+	CreateReferences(ctx, &rotated)
 
-	return entity.Uri()
+	confirmation, err := createReplacement(ctx, rotated.Uri(), entity.Uri(), rotated.Uri(), newKey)
+	if err != nil {
+		return references.ERROR_URI, err
+	}
+	if _, err := createReplacement(ctx, rotated.Uri(), entity.Uri(), entity.Uri(), oldKey, confirmation.Uri()); err != nil {
+		return references.ERROR_URI, err
+	}
+
+	return rotated.Uri(), nil
+}
+
+// Returns the organisation that issued an entity's certificate and its private key, or nil for a self-signed entity.
+func fetchIssuer(ctx context.Context, entity entities.Entity) (*entities.Entity, crypto.Signer, error) {
+	if entity.IsSelfSigned() {
+		return nil, nil, nil
+	}
+	organisation, err := ActiveDataStore.FetchEntity(ctx, entity.Parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	organisationKey, err := FetchPrivateKey(entity.Parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &organisation, organisationKey, nil
 }
 
+// Creates an assertion by the issuer that one entity replaces another.
+func createReplacement(ctx context.Context, successor references.HashUri, original references.HashUri, issuer references.HashUri, privateKey crypto.Signer, basis ...references.HashUri) (*assertions.Assertion, error) {
+	replacement := assertions.NewAssertion(assertions.Replaces)
+	replacement.Subject = successor.String()
+	replacement.Object = original.String()
+	replacement.Issuer = issuer.String()
+	replacement.Confidence = 1.0
+	for _, uri := range basis {
+		replacement.Basis = append(replacement.Basis, uri.String())
+	}
+	return CreateSignedAssertion(ctx, replacement, privateKey)
+}
+
+// Creates a document from its XML content, along with the statements and assertions for any spans that
+// make new assertions, then signs it as its author and stores it.
+//
+// The author of the document is the signing entity, which signs the canonical XML of the finished document
+// with a detached signature. Documents that name any other entity as their author are rejected.
 func CreateDocumentAndAssertions(ctx context.Context, content string, entityUri references.HashUri) (*docs.Document, error) {
 	entity, err := ActiveDataStore.FetchEntity(ctx, entityUri)
 	if err != nil {
 		return nil, err
 	}
+	privateKey, err := FetchPrivateKey(entity.Uri())
+	if err != nil {
+		return nil, err
+	}
 
-	doc, _ := docs.MakeDocument(content)
+	doc, err := docs.MakeDocument(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
 
 	author := &doc.Metadata.Author
 	if author.Entity == "" {
 		log.DebugfX(ctx, "Setting document author to %s", entity.CommonName)
 		author.Entity = entity.Uri().String()
 		author.Name = entity.CommonName
+	} else if references.UriFromString(author.Entity).Hash() != entity.Uri().Hash() {
+		return nil, fmt.Errorf("%w: %s", ErrDocumentAuthor, author.Entity)
 	}
 
+	// Every new assertion is checked before any are made, so that an invalid document leaves nothing behind
+	type spanAssertion struct {
+		span       *docs.Span
+		kind       assertions.AssertionType
+		confidence float64
+	}
+	pending := make([]spanAssertion, 0)
 	for i := range doc.Sections {
 		for j := range doc.Sections[i].Paragraphs {
 			for k := range doc.Sections[i].Paragraphs[j].Spans {
 				span := &doc.Sections[i].Paragraphs[j].Spans[k]
 				if span.Assertion != "" && !strings.HasPrefix(span.Assertion, "hash://") {
-					parts := strings.Split(span.Assertion, " ")
-					assertionType := assertions.AssertionTypeOf(parts[0])
-					confidence, _ := strconv.ParseFloat(parts[1], 32)
-
-					assertion, _ := CreateStatementAndAssertion(ctx, span.Body, entityUri, assertionType, confidence)
-
-					span.Assertion = assertion.Uri().String()
+					kind, confidence, err := parseSpanAssertion(span.Assertion)
+					if err != nil {
+						return nil, err
+					}
+					pending = append(pending, spanAssertion{span: span, kind: kind, confidence: confidence})
 				}
 			}
 		}
 	}
 
-	doc.UpdateContent()
+	for _, p := range pending {
+		assertion, err := CreateStatementAndAssertion(ctx, p.span.Body, entityUri, p.kind, p.confidence)
+		if err != nil {
+			return nil, err
+		}
+		p.span.Assertion = assertion.Uri().String()
+	}
+
+	if err := doc.Sign(entity, privateKey); err != nil {
+		return nil, err
+	}
 
 	ActiveDataStore.Store(ctx, doc)
 
@@ -174,3 +567,21 @@ func CreateDocumentAndAssertions(ctx context.Context, content string, entityUri
 
 	return doc, nil
 }
+
+// Parses the assertion attribute of a span that makes a new assertion about the span's text, which is
+// the category of the assertion followed by its confidence, such as "IsTrue 0.9".
+func parseSpanAssertion(attribute string) (assertions.AssertionType, float64, error) {
+	parts := strings.Fields(attribute)
+	if len(parts) != 2 {
+		return assertions.Unknown, 0, fmt.Errorf("%w: span assertion must be a category and confidence: %q", ErrInvalidDocument, attribute)
+	}
+	kind := assertions.AssertionTypeOf(parts[0])
+	if kind == assertions.Unknown || !kind.Category().AllowsSubject("statement") {
+		return assertions.Unknown, 0, fmt.Errorf("%w: unknown category for a statement: %s", ErrInvalidDocument, parts[0])
+	}
+	confidence, err := strconv.ParseFloat(parts[1], 32)
+	if err != nil || confidence < 0 || confidence > 1 {
+		return assertions.Unknown, 0, fmt.Errorf("%w: confidence must be from 0 to 1: %s", ErrInvalidDocument, parts[1])
+	}
+	return kind, confidence, nil
+}
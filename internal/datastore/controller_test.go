@@ -4,11 +4,13 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"errors"
 	"math/big"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"silvatek.uk/trustedassertions/internal/assertions"
 	"silvatek.uk/trustedassertions/internal/docs"
 	"silvatek.uk/trustedassertions/internal/entities"
@@ -79,14 +81,17 @@ func TestCreateDocumentAndAssertions(t *testing.T) {
 		t.Errorf("Did not find assertion in document: %s", xml)
 	}
 
-	_, err = ActiveDataStore.FetchDocument(ctx, doc.Uri())
+	stored, err := ActiveDataStore.FetchDocument(ctx, doc.Uri())
 	if err != nil {
 		t.Errorf("Could not load document: %v", err)
 	}
-	_, err = ActiveDataStore.FetchEntity(ctx, references.UriFromString(doc.Metadata.Author.Entity))
+	author, err := ActiveDataStore.FetchEntity(ctx, references.UriFromString(doc.Metadata.Author.Entity))
 	if err != nil {
 		t.Errorf("Could not load author entity: %v", err)
 	}
+	if err := stored.VerifySignature(author); err != nil {
+		t.Errorf("Stored document should be signed by its author: %v", err)
+	}
 	_, err = ActiveDataStore.FetchAssertion(ctx, references.UriFromString(doc.Sections[0].Paragraphs[0].Spans[0].Assertion))
 	if err != nil {
 		t.Errorf("Could not load assertion 1: %v", err)
@@ -97,6 +102,53 @@ func TestCreateDocumentAndAssertions(t *testing.T) {
 	}
 }
 
+func TestCreateDocumentForAnotherAuthor(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	author := CreateEntityWithKey(ctx, "Real author")
+	impostor := CreateEntityWithKey(ctx, "Impostor")
+	content := `<document><metadata><author entity="` + author.String() + `">Real author</author><title>Not mine</title></metadata></document>`
+
+	if _, err := CreateDocumentAndAssertions(ctx, content, impostor); !errors.Is(err, ErrDocumentAuthor) {
+		t.Errorf("Expected error for a document naming another author: %v", err)
+	}
+	if _, err := CreateDocumentAndAssertions(ctx, content, author); err != nil {
+		t.Errorf("Error creating document as its named author: %v", err)
+	}
+}
+
+func TestCreateInvalidDocument(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	entityUri := CreateEntityWithKey(ctx, "Unit Tester")
+	spanDoc := func(attribute string) string {
+		return `<document><metadata><title>Spans</title></metadata><section><paragraph><span assertion="` + attribute + `">Some claim</span></paragraph></section></document>`
+	}
+
+	for _, content := range []string{"<document><metadata>", spanDoc("IsTrue"), spanDoc("IsNonsense 0.9"), spanDoc("IsTrusted 0.9"), spanDoc("IsTrue high")} {
+		if _, err := CreateDocumentAndAssertions(ctx, content, entityUri); !errors.Is(err, ErrInvalidDocument) {
+			t.Errorf("Expected invalid document error for %s: %v", content, err)
+		}
+	}
+	if uris, _ := ActiveDataStore.FetchUris(ctx, "assertion"); len(uris) != 0 {
+		t.Errorf("Invalid documents should not create assertions: %v", uris)
+	}
+
+	// Errors creating the assertions are returned rather than leaving the span without a URI
+	privateKey, _ := entities.GenerateKey(entities.DefaultKeyAlgorithm)
+	expired := entities.Entity{CommonName: "Expired", Issued: time.Now().AddDate(-3, 0, 0)}
+	expired.MakeCertificate(privateKey)
+	ActiveDataStore.Store(ctx, &expired)
+	StorePrivateKey(expired.Uri(), privateKey)
+	if _, err := CreateDocumentAndAssertions(ctx, spanDoc("IsTrue 0.9"), expired.Uri()); !errors.Is(err, entities.ErrCertificateNotValid) {
+		t.Errorf("Expected certificate error for an expired author: %v", err)
+	}
+}
+
 func TestCreateStatement(t *testing.T) {
 	ActiveDataStore = NewInMemoryDataStore()
 	ctx := context.Background()
@@ -184,6 +236,17 @@ func TestMakeAssertionReferenceSummary(t *testing.T) {
 	if ref.Summary != "Tester claims that 'Testing' is true" {
 		t.Errorf("Unexpected reference summary: %s", ref.Summary)
 	}
+	if ref.Category != assertions.IsTrue.String() {
+		t.Errorf("Unexpected reference category: %s", ref.Category)
+	}
+
+	subject := references.UriFromString(assertion.Subject)
+	if found, _ := ActiveDataStore.FetchRefsOfCategory(ctx, subject, assertions.IsTrue.String()); len(found) != 1 {
+		t.Errorf("Expected one IsTrue reference to the statement: %v", found)
+	}
+	if found, _ := ActiveDataStore.FetchRefsOfCategory(ctx, subject, assertions.IsFalse.String()); len(found) != 0 {
+		t.Errorf("Expected no IsFalse references to the statement: %v", found)
+	}
 }
 
 func TestMakeUnknownReferenceSummary(t *testing.T) {
@@ -196,3 +259,387 @@ func TestMakeUnknownReferenceSummary(t *testing.T) {
 		t.Errorf("Unexpected reference summary: %s", ref.Summary)
 	}
 }
+
+func TestCreateAssertionBasis(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	entityUri := CreateEntityWithKey(ctx, "Unit Tester")
+	b64key, _ := ActiveDataStore.FetchKey(entityUri)
+	privateKey := entities.PrivateKeyFromString(b64key)
+
+	supporting, err := CreateStatementAndAssertion(ctx, "Water is wet", entityUri, assertions.IsTrue, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statement := CreateStatement(ctx, "Rain is wet")
+
+	assertion, err := CreateAssertion(ctx, statement, entityUri, assertions.IsTrue, 0.8, privateKey, supporting.Uri())
+	if err != nil {
+		t.Fatalf("Error creating assertion with basis: %v", err)
+	}
+	if len(assertion.Basis) != 1 || !references.UriFromString(assertion.Basis[0]).Equals(supporting.Uri()) {
+		t.Errorf("Unexpected basis: %v", assertion.Basis)
+	}
+
+	refs, _ := ActiveDataStore.FetchRefs(ctx, supporting.Uri())
+	found := false
+	for _, ref := range refs {
+		found = found || ref.Source.Equals(assertion.Uri())
+	}
+	if !found {
+		t.Error("Basis assertion should be referenced by the assertion citing it")
+	}
+
+	_, err = CreateAssertion(ctx, statement, entityUri, assertions.IsTrue, 0.8, privateKey, statement)
+	if !errors.Is(err, ErrInvalidBasis) {
+		t.Errorf("Expected error for a basis that is not an assertion: %v", err)
+	}
+
+	_, err = CreateAssertion(ctx, statement, entityUri, assertions.IsTrue, 0.8, privateKey, references.MakeUri("1234", "assertion"))
+	if !errors.Is(err, ErrInvalidBasis) {
+		t.Errorf("Expected error for a basis that does not exist: %v", err)
+	}
+}
+
+func TestCreateAssertionValidity(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	entityUri := CreateEntityWithKey(ctx, "Unit Tester")
+	b64key, _ := ActiveDataStore.FetchKey(entityUri)
+	statement := CreateStatement(ctx, "Valid for a while")
+
+	claims := assertions.NewAssertion(assertions.IsTrue)
+	claims.Subject = statement.String()
+	claims.Issuer = entityUri.String()
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	assertion, err := CreateSignedAssertion(ctx, claims, entities.PrivateKeyFromString(b64key))
+	if err != nil {
+		t.Fatalf("Error creating assertion: %v", err)
+	}
+	if !assertion.NotBefore.Equal(assertion.IssuedAt.Time) || !assertion.IsActive() {
+		t.Errorf("Assertion should be active from when it was issued: %v", assertion.NotBefore)
+	}
+
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	if _, err := CreateSignedAssertion(ctx, claims, entities.PrivateKeyFromString(b64key)); !errors.Is(err, ErrInvalidValidity) {
+		t.Errorf("Expected error for an assertion that expires before it is valid: %v", err)
+	}
+}
+
+func TestCreateRetraction(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	issuerUri := CreateEntityWithKey(ctx, "Issuer")
+	otherUri := CreateEntityWithKey(ctx, "Someone else")
+	issuerKey, _ := ActiveDataStore.FetchKey(issuerUri)
+	otherKey, _ := ActiveDataStore.FetchKey(otherUri)
+
+	original, err := CreateStatementAndAssertion(ctx, "Changed my mind", issuerUri, assertions.IsTrue, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = CreateAssertion(ctx, original.Uri(), otherUri, assertions.Retracts, 1.0, entities.PrivateKeyFromString(otherKey))
+	if !errors.Is(err, ErrInvalidRetraction) {
+		t.Errorf("Expected error retracting another entity's assertion: %v", err)
+	}
+
+	statement := references.UriFromString(original.Subject)
+	_, err = CreateAssertion(ctx, statement, issuerUri, assertions.Retracts, 1.0, entities.PrivateKeyFromString(issuerKey))
+	if !errors.Is(err, ErrInvalidRetraction) {
+		t.Errorf("Expected error retracting something other than an assertion: %v", err)
+	}
+
+	retraction, err := CreateAssertion(ctx, original.Uri(), issuerUri, assertions.Retracts, 1.0, entities.PrivateKeyFromString(issuerKey))
+	if err != nil {
+		t.Fatalf("Error retracting assertion: %v", err)
+	}
+	found, retracted := assertions.RetractionOf(ctx, ActiveDataStore, *original)
+	if !retracted || !found.Uri().Equals(retraction.Uri()) {
+		t.Error("Retraction not found for retracted assertion")
+	}
+}
+
+func TestCreateMemberEntity(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	organisation := CreateEntityWithKey(ctx, "Organisation")
+	member, err := CreateMemberEntity(ctx, "Member", entities.ECDSA, organisation)
+	if err != nil {
+		t.Fatalf("Error creating member: %v", err)
+	}
+
+	entity, _ := ActiveDataStore.FetchEntity(ctx, member)
+	chain, err := assertions.IssuerChain(ctx, ActiveDataStore, entity)
+	if err != nil || len(chain) != 1 || !chain[0].Uri().Equals(organisation) {
+		t.Errorf("Unexpected issuer chain: %v %v", chain, err)
+	}
+	members := assertions.MembersOf(ctx, ActiveDataStore, organisation)
+	if len(members) != 1 || !members[0].Uri().Equals(member) {
+		t.Errorf("Unexpected members: %v", members)
+	}
+
+	if _, err := CreateStatementAndAssertion(ctx, "Members can make assertions", member, assertions.IsTrue, 0.9); err != nil {
+		t.Errorf("Error making assertion as member: %v", err)
+	}
+
+	// A member of an organisation that is not known cannot have its assertions verified
+	orgKey, _ := entities.GenerateKey(entities.ECDSA)
+	unknown := entities.Entity{CommonName: "Unknown organisation"}
+	unknown.MakeCertificate(orgKey)
+	memberKey, _ := entities.GenerateKey(entities.ECDSA)
+	orphan := entities.Entity{CommonName: "Orphan"}
+	orphan.MakeMemberCertificate(memberKey, unknown, orgKey)
+	ActiveDataStore.Store(ctx, &orphan)
+	ActiveDataStore.StoreKey(orphan.Uri(), entities.PrivateKeyToString(memberKey))
+
+	assertion, _ := CreateStatementAndAssertion(ctx, "Nobody vouches for me", orphan.Uri(), assertions.IsTrue, 0.9)
+	if _, err := ActiveDataStore.FetchAssertion(ctx, assertion.Uri()); !errors.Is(err, assertions.ErrInvalidChain) {
+		t.Errorf("Expected invalid chain error for orphan's assertion: %v", err)
+	}
+}
+
+func TestRenewEntity(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	privateKey, _ := entities.GenerateKey(entities.RSA)
+	expired := entities.Entity{CommonName: "Expired", Issued: time.Now().Add(-entities.CertificateLifetime - time.Hour)}
+	expired.MakeCertificate(privateKey)
+	ActiveDataStore.Store(ctx, &expired)
+	ActiveDataStore.StoreKey(expired.Uri(), entities.PrivateKeyToString(privateKey))
+
+	if _, err := CreateStatementAndAssertion(ctx, "Too late", expired.Uri(), assertions.IsTrue, 0.9); !errors.Is(err, entities.ErrCertificateNotValid) {
+		t.Errorf("Expected certificate not valid error for expired entity: %v", err)
+	}
+
+	// Without the issuer's certificate, its validity cannot be checked
+	unstored := entities.Entity{CommonName: "Unstored"}
+	unstored.MakeCertificate(privateKey)
+	statement := CreateStatement(ctx, "Nobody knows who I am")
+	if _, err := CreateAssertion(ctx, statement, unstored.Uri(), assertions.IsTrue, 0.9, privateKey); err == nil {
+		t.Error("Expected error creating an assertion for an entity that is not stored")
+	}
+
+	renewedUri, err := RenewEntity(ctx, expired.Uri())
+	if err != nil {
+		t.Fatalf("Error renewing entity: %v", err)
+	}
+	renewed, _ := ActiveDataStore.FetchEntity(ctx, renewedUri)
+	if found, ok := assertions.SuccessorOf(ctx, ActiveDataStore, expired); !ok || !found.Successor.Uri().Equals(renewedUri) || found.Rotated {
+		t.Errorf("Expired entity should link to its renewal: %v", found.Successor.Uri())
+	}
+	if found, ok := assertions.PredecessorOf(ctx, ActiveDataStore, renewed); !ok || !found.Original.Uri().Equals(expired.Uri()) {
+		t.Errorf("Renewed entity should link to the original: %v", found.Original.Uri())
+	}
+	if _, err := CreateStatementAndAssertion(ctx, "Back again", renewedUri, assertions.IsTrue, 0.9); err != nil {
+		t.Errorf("Error making assertion as renewed entity: %v", err)
+	}
+
+	// A member is renewed by its organisation
+	organisation := CreateEntityWithKey(ctx, "Organisation")
+	member, _ := CreateMemberEntity(ctx, "Member", entities.ECDSA, organisation)
+	renewedMember, err := RenewEntity(ctx, member)
+	if err != nil {
+		t.Fatalf("Error renewing member: %v", err)
+	}
+	entity, _ := ActiveDataStore.FetchEntity(ctx, renewedMember)
+	if chain, err := assertions.IssuerChain(ctx, ActiveDataStore, entity); err != nil || len(chain) != 1 {
+		t.Errorf("Renewed member should be issued by its organisation: %v", err)
+	}
+}
+
+func TestRotateEntity(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	original := CreateEntityWithKey(ctx, "Original")
+	rotatedUri, err := RotateEntity(ctx, original, entities.Ed25519)
+	if err != nil {
+		t.Fatalf("Error rotating key: %v", err)
+	}
+
+	entity, _ := ActiveDataStore.FetchEntity(ctx, original)
+	rotated, _ := ActiveDataStore.FetchEntity(ctx, rotatedUri)
+	if rotated.CommonName != "Original" || rotated.KeyAlgorithm() != entities.Ed25519 || rotated.HasSameKey(entity) {
+		t.Errorf("Rotated entity should have the same name and a new key: %s %s", rotated.CommonName, rotated.KeyAlgorithm())
+	}
+	succession, found := assertions.SuccessorOf(ctx, ActiveDataStore, entity)
+	if !found || !succession.Rotated || !succession.Successor.Uri().Equals(rotatedUri) {
+		t.Errorf("Original entity should link to its rotated key: %v", succession)
+	}
+	if succession, found := assertions.PredecessorOf(ctx, ActiveDataStore, rotated); !found || !succession.Original.Uri().Equals(original) {
+		t.Errorf("Rotated entity should link to the original: %v", succession)
+	}
+	if _, err := CreateStatementAndAssertion(ctx, "Signed with the new key", rotatedUri, assertions.IsTrue, 0.9); err != nil {
+		t.Errorf("Error making assertion with rotated key: %v", err)
+	}
+
+	// A claim by another entity to replace the original, without the original's signature, is not a rotation
+	impostorUri := CreateEntityWithKey(ctx, "Original")
+	impostor, _ := ActiveDataStore.FetchEntity(ctx, impostorUri)
+	b64key, _ := ActiveDataStore.FetchKey(impostorUri)
+	createReplacement(ctx, impostorUri, original, impostorUri, entities.PrivateKeyFromString(b64key))
+	if succession, found := assertions.PredecessorOf(ctx, ActiveDataStore, impostor); found {
+		t.Errorf("Impostor should not be linked to the original: %v", succession)
+	}
+}
+
+func TestStoreSignedAssertion(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	issuerUri := CreateEntityWithKey(ctx, "Offline signer")
+	otherUri := CreateEntityWithKey(ctx, "Someone else")
+	issuerKey, _ := ActiveDataStore.FetchKey(issuerUri)
+	otherKey, _ := ActiveDataStore.FetchKey(otherUri)
+	statement := CreateStatement(ctx, "Signed elsewhere")
+
+	claims := assertions.NewAssertion(assertions.IsTrue)
+	claims.Subject = statement.String()
+	claims.Issuer = issuerUri.String()
+	claims.IssuedAt = jwt.NewNumericDate(time.Now())
+	claims.MakeJwt(entities.PrivateKeyFromString(issuerKey))
+
+	assertion, err := StoreSignedAssertion(ctx, claims.Content()+"\n")
+	if err != nil {
+		t.Fatalf("Error storing signed assertion: %v", err)
+	}
+	if assertion.Content() != claims.Content() || !assertion.Uri().Equals(claims.Uri()) {
+		t.Errorf("Signed assertion should be stored as it was submitted: %s", assertion.Uri())
+	}
+	refs, _ := ActiveDataStore.FetchRefs(ctx, statement)
+	if len(refs) != 1 || !refs[0].Source.Equals(assertion.Uri()) {
+		t.Errorf("Statement should be referenced by the signed assertion: %v", refs)
+	}
+
+	again, err := StoreSignedAssertion(ctx, claims.Content())
+	if err != nil || !again.Uri().Equals(assertion.Uri()) {
+		t.Errorf("Resubmitting a stored assertion should return it: %v", err)
+	}
+	if refs, _ := ActiveDataStore.FetchRefs(ctx, statement); len(refs) != 1 {
+		t.Errorf("Resubmitting a stored assertion should not add references: %v", refs)
+	}
+
+	forged := claims
+	forged.MakeJwt(entities.PrivateKeyFromString(otherKey))
+	if _, err := StoreSignedAssertion(ctx, forged.Content()); !errors.Is(err, ErrInvalidSubmission) {
+		t.Errorf("Expected error for an assertion not signed by its issuer: %v", err)
+	}
+
+	undated := assertions.NewAssertion(assertions.IsFalse)
+	undated.Subject = statement.String()
+	undated.Issuer = issuerUri.String()
+	undated.MakeJwt(entities.PrivateKeyFromString(issuerKey))
+	if _, err := StoreSignedAssertion(ctx, undated.Content()); !errors.Is(err, ErrInvalidSubmission) {
+		t.Errorf("Expected error for an assertion with no issue time: %v", err)
+	}
+
+	unsupported := claims
+	unsupported.Basis = []string{statement.String()}
+	unsupported.MakeJwt(entities.PrivateKeyFromString(issuerKey))
+	if _, err := StoreSignedAssertion(ctx, unsupported.Content()); !errors.Is(err, ErrInvalidBasis) {
+		t.Errorf("Expected error for a basis that is not an assertion: %v", err)
+	}
+
+	if _, err := StoreSignedAssertion(ctx, "Not a JWT"); !errors.Is(err, ErrInvalidSubmission) {
+		t.Errorf("Expected error for content that is not a JWT: %v", err)
+	}
+}
+
+func TestCreateCoseAssertion(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	issuerUri := CreateEntityWithKey(ctx, "Compact signer")
+	issuerKey, _ := ActiveDataStore.FetchKey(issuerUri)
+	statement := CreateStatement(ctx, "Small enough for a QR code")
+
+	claims := assertions.NewAssertion(assertions.IsTrue)
+	claims.Subject = statement.String()
+	claims.Issuer = issuerUri.String()
+
+	assertion, err := CreateSignedAssertionAs(ctx, claims, entities.PrivateKeyFromString(issuerKey), assertions.CoseFormat)
+	if err != nil {
+		t.Fatalf("Error creating COSE assertion: %v", err)
+	}
+	if assertion.Uri().Kind() != assertions.COSE_KIND {
+		t.Errorf("Unexpected kind of COSE assertion: %s", assertion.Uri())
+	}
+
+	fetched, err := ActiveDataStore.FetchAssertion(ctx, assertion.Uri())
+	if err != nil || fetched.Format() != assertions.CoseFormat || fetched.Subject != statement.String() {
+		t.Errorf("Error fetching COSE assertion: %v", err)
+	}
+	item, err := ActiveDataStore.Fetch(ctx, assertion.Uri())
+	if err != nil || item.Type() != assertions.COSE_TYPE {
+		t.Errorf("Unexpected type of fetched COSE assertion: %v", err)
+	}
+
+	// COSE assertions can be cited and retracted in the same way as JWT assertions
+	endorsement, err := CreateAssertion(ctx, CreateStatement(ctx, "Tiny"), issuerUri, assertions.IsTrue, 0.5, entities.PrivateKeyFromString(issuerKey), references.UriFromString(assertion.Uri().Hash()))
+	if err != nil || endorsement.Basis[0] != assertion.Uri().String() {
+		t.Errorf("Error citing COSE assertion as a basis: %v", err)
+	}
+	retraction, err := CreateAssertion(ctx, assertion.Uri(), issuerUri, assertions.Retracts, 1.0, entities.PrivateKeyFromString(issuerKey))
+	if err != nil {
+		t.Fatalf("Error retracting COSE assertion: %v", err)
+	}
+	if found, retracted := assertions.RetractionOf(ctx, ActiveDataStore, fetched); !retracted || !found.Uri().Equals(retraction.Uri()) {
+		t.Error("Retraction not found for retracted COSE assertion")
+	}
+
+	// A compromise declared in COSE applies to assertions in either format
+	compromise := assertions.NewAssertion(assertions.IsCompromised)
+	compromise.Subject = issuerUri.String()
+	compromise.Issuer = issuerUri.String()
+	compromise.Since = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	if _, err := CreateSignedAssertionAs(ctx, compromise, entities.PrivateKeyFromString(issuerKey), assertions.CoseFormat); err != nil {
+		t.Fatalf("Error declaring compromise in COSE: %v", err)
+	}
+	if _, err := CreateAssertion(ctx, statement, issuerUri, assertions.IsFalse, 0.5, entities.PrivateKeyFromString(issuerKey)); !errors.Is(err, assertions.ErrCompromised) {
+		t.Errorf("Expected compromised key error signing after COSE compromise declaration: %v", err)
+	}
+	later := assertions.NewAssertion(assertions.IsFalse)
+	later.Subject = statement.String()
+	later.Issuer = issuerUri.String()
+	later.IssuedAt = jwt.NewNumericDate(time.Now())
+	later.MakeJwt(entities.PrivateKeyFromString(issuerKey))
+	ActiveDataStore.Store(ctx, &later)
+	if _, err := ActiveDataStore.FetchAssertion(ctx, later.Uri()); !errors.Is(err, assertions.ErrCompromised) {
+		t.Errorf("Expected compromised key error after COSE compromise declaration: %v", err)
+	}
+}
+
+func TestStoreSignedCoseAssertion(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	issuerUri := CreateEntityWithKey(ctx, "Compact signer")
+	issuerKey, _ := ActiveDataStore.FetchKey(issuerUri)
+
+	signed := assertions.NewAssertion(assertions.IsTrue)
+	signed.Subject = CreateStatement(ctx, "Signed elsewhere in COSE").String()
+	signed.Issuer = issuerUri.String()
+	signed.IssuedAt = jwt.NewNumericDate(time.Now())
+	signed.Confidence = 0.7
+	signed.MakeCose(entities.PrivateKeyFromString(issuerKey))
+	submitted, err := StoreSignedAssertion(ctx, signed.Content())
+	if err != nil || submitted.Format() != assertions.CoseFormat {
+		t.Errorf("Error storing pre-signed COSE assertion: %v", err)
+	}
+}
@@ -21,10 +21,12 @@ type DataStore interface {
 	FetchEntity(ctx context.Context, key refs.HashUri) (entities.Entity, error)
 	FetchAssertion(ctx context.Context, key refs.HashUri) (assertions.Assertion, error)
 	FetchDocument(ctx context.Context, key refs.HashUri) (docs.Document, error)
+	FetchContent(ctx context.Context, key refs.HashUri) (string, error)
 	Store(ctx context.Context, value refs.Referenceable)
 	StoreRaw(uri refs.HashUri, content string)
 
 	FetchRefs(ctx context.Context, key refs.HashUri) ([]refs.Reference, error)
+	FetchRefsOfCategory(ctx context.Context, key refs.HashUri, category string) ([]refs.Reference, error)
 	StoreRef(ctx context.Context, reference refs.Reference)
 
 	StoreKey(entityUri refs.HashUri, key string)
@@ -32,10 +34,12 @@ type DataStore interface {
 	StoreRegistration(ctx context.Context, reg auth.Registration) error
 
 	FetchKey(entityUri refs.HashUri) (string, error)
+	FetchKeyUris(ctx context.Context) ([]refs.HashUri, error)
 	FetchUser(ctx context.Context, id string) (auth.User, error)
 	FetchRegistration(ctx context.Context, code string) (auth.Registration, error)
 
 	Search(ctx context.Context, query string) ([]SearchResult, error)
+	FetchUris(ctx context.Context, dataType string) ([]refs.HashUri, error)
 
 	Reindex()
 }
@@ -15,6 +15,7 @@ import (
 	"silvatek.uk/trustedassertions/internal/auth"
 	"silvatek.uk/trustedassertions/internal/docs"
 	"silvatek.uk/trustedassertions/internal/entities"
+	"silvatek.uk/trustedassertions/internal/keyvault"
 	ref "silvatek.uk/trustedassertions/internal/references"
 	"silvatek.uk/trustedassertions/internal/search"
 	"silvatek.uk/trustedassertions/internal/statements"
@@ -149,7 +150,7 @@ func (fs *FireStore) Store(ctx context.Context, value ref.Referenceable) {
 func (fs *FireStore) StoreKey(entityUri ref.HashUri, key string) {
 	data := make(map[string]interface{})
 	data["entity"] = entityUri.Unadorned()
-	data["encoding"] = "base64"
+	data["encoding"] = keyvault.EncodingOf(key)
 	data["key"] = key
 
 	fs.store(KeyCollection, entityUri.Escaped(), data)
@@ -162,6 +163,7 @@ func (fs *FireStore) StoreRef(ctx context.Context, reference ref.Reference) {
 	data["source"] = reference.Source.String()
 	data["target"] = reference.Target.String()
 	data["summary"] = reference.Summary
+	data["category"] = reference.Category
 	data["updated"] = time.Now().Format(time.RFC3339)
 
 	refs := client.Collection(MainCollection).Doc(reference.Target.Escaped()).Collection("refs")
@@ -277,6 +279,14 @@ type KeyRecord struct {
 	Encoding string `json:"encoding"`
 }
 
+func (fs *FireStore) FetchContent(ctx context.Context, uri ref.HashUri) (string, error) {
+	record, err := fs.fetch(ctx, uri)
+	if err != nil {
+		return "", err
+	}
+	return record.Content, nil
+}
+
 func (fs *FireStore) FetchKey(entityUri ref.HashUri) (string, error) {
 	ctx := context.TODO()
 	client := fs.client(ctx)
@@ -292,32 +302,76 @@ func (fs *FireStore) FetchKey(entityUri ref.HashUri) (string, error) {
 	}
 }
 
+// Returns the URIs of the entities whose private keys are stored, read from the keys themselves so that
+// keys are found even if their entities are not stored with the expected data type.
+func (fs *FireStore) FetchKeyUris(ctx context.Context) ([]ref.HashUri, error) {
+	client := fs.client(ctx)
+
+	uris := make([]ref.HashUri, 0)
+	docs := client.Collection(KeyCollection).Documents(ctx)
+	for {
+		doc, err := docs.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return uris, err
+		}
+
+		record := KeyRecord{}
+		doc.DataTo(&record)
+		if record.Entity != "" {
+			uris = append(uris, ref.UriFromString(record.Entity))
+		} else {
+			uris = append(uris, ref.UnescapeUri(doc.Ref.ID, ""))
+		}
+	}
+	return uris, nil
+}
+
 type DbReference struct {
-	Source  string `json:"source"`
-	Target  string `json:"target"`
-	Type    string `json:"type"`
-	Summary string `json:"summary"`
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Type     string `json:"type"`
+	Summary  string `json:"summary"`
+	Category string `json:"category"`
 }
 
 func (fs *FireStore) FetchRefs(ctx context.Context, uri ref.HashUri) ([]ref.Reference, error) {
 	log.DebugfX(ctx, "Fetching references for %s", uri.String())
 
 	client := fs.client(ctx)
-	results := make([]ref.Reference, 0)
-
 	refs := client.Collection(MainCollection).Doc(uri.Escaped()).Collection("refs").Documents(ctx)
+	return readRefs(ctx, refs)
+}
+
+// Fetches the references to a URI from assertions of the specified category, using the category
+// stored with each reference, so that the other references need not be read.
+func (fs *FireStore) FetchRefsOfCategory(ctx context.Context, uri ref.HashUri, category string) ([]ref.Reference, error) {
+	log.DebugfX(ctx, "Fetching %s references for %s", category, uri.String())
+
+	client := fs.client(ctx)
+	refs := client.Collection(MainCollection).Doc(uri.Escaped()).Collection("refs").Where("category", "==", category).Documents(ctx)
+	return readRefs(ctx, refs)
+}
+
+func readRefs(ctx context.Context, refs *firestore.DocumentIterator) ([]ref.Reference, error) {
+	results := make([]ref.Reference, 0)
 	for {
 		doc, err := refs.Next()
 		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return results, err
+		}
 		record := DbReference{}
 		doc.DataTo(&record)
 
 		reference := ref.Reference{
-			Source:  ref.UriFromString(record.Source),
-			Target:  ref.UriFromString(record.Target),
-			Summary: record.Summary,
+			Source:   ref.UriFromString(record.Source),
+			Target:   ref.UriFromString(record.Target),
+			Summary:  record.Summary,
+			Category: record.Category,
 		}
 
 		results = append(results, reference)
@@ -429,6 +483,27 @@ func (fs *FireStore) query(ctx context.Context, fieldName string, operator strin
 	return results, nil
 }
 
+// Returns the URIs of all the stored values of the specified data type.
+func (fs *FireStore) FetchUris(ctx context.Context, dataType string) ([]ref.HashUri, error) {
+	uris := make([]ref.HashUri, 0)
+	if dataType == "" {
+		return uris, nil
+	}
+
+	// Values are stored with a capitalised type name, but raw values with the lower-case URI kind
+	dataTypes := []string{strings.ToLower(dataType), strings.ToUpper(dataType[:1]) + strings.ToLower(dataType[1:])}
+
+	records, err := fs.query(ctx, "datatype", "in", dataTypes)
+	if err != nil {
+		return uris, err
+	}
+
+	for _, record := range records {
+		uris = append(uris, ref.UriFromString(record.Uri))
+	}
+	return uris, nil
+}
+
 func explainString(plan *firestore.ExplainMetrics) string {
 	var sb strings.Builder
 	sb.WriteString("Execution duration=")
@@ -452,7 +527,7 @@ func searchDocs(docs DocFetcher, query string) []SearchResult {
 			break
 		}
 
-		if strings.ToLower(record.DataType) == "assertion" {
+		if assertions.IsAssertionKind(strings.ToLower(record.DataType)) {
 			// Don't bother searching assertions as they don't have textual content
 			continue
 		}
@@ -508,7 +583,9 @@ func (fs *FireStore) Reindex() {
 		record := DbRecord{}
 		doc.DataTo(&record)
 
-		if record.DataType == "assertion" {
+		fs.categoriseRefs(ctx, doc.Ref)
+
+		if assertions.IsAssertionKind(strings.ToLower(record.DataType)) {
 			continue
 		}
 
@@ -527,6 +604,37 @@ func (fs *FireStore) Reindex() {
 	log.Info("Reindex complete.")
 }
 
+// Sets the category of each reference from an assertion that was stored before references had categories.
+func (fs *FireStore) categoriseRefs(ctx context.Context, doc *firestore.DocumentRef) {
+	refs := doc.Collection("refs").Documents(ctx)
+	for {
+		refDoc, err := refs.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.ErrorfX(ctx, "Error reading references of %s: %v", doc.ID, err)
+			return
+		}
+		record := DbReference{}
+		refDoc.DataTo(&record)
+
+		source := ref.UriFromString(record.Source)
+		if record.Category != "" || !assertions.IsAssertionKind(source.Kind()) {
+			continue
+		}
+		content, err := fs.FetchContent(ctx, source)
+		if err != nil {
+			continue
+		}
+		claims, err := assertions.ParseUnverified(content)
+		if err != nil {
+			continue
+		}
+		refDoc.Ref.Update(ctx, []firestore.Update{{Path: "category", Value: claims.Category}})
+	}
+}
+
 func (fs *FireStore) StoreRegistration(ctx context.Context, reg auth.Registration) error {
 	client := fs.client(ctx)
 
@@ -0,0 +1,85 @@
+package datastore
+
+import (
+	"context"
+	"crypto"
+	"errors"
+
+	"silvatek.uk/trustedassertions/internal/entities"
+	"silvatek.uk/trustedassertions/internal/keyvault"
+	"silvatek.uk/trustedassertions/internal/references"
+)
+
+// Wraps an entity's private key with the active key vault and stores it in the active datastore.
+func StorePrivateKey(entityUri references.HashUri, privateKey crypto.Signer) error {
+	encoded := entities.PrivateKeyToString(privateKey)
+	if encoded == "" {
+		return errors.New("unable to encode private key for " + entityUri.String())
+	}
+	wrapped, err := keyvault.ActiveKeyVault.Wrap(entityUri, encoded)
+	if err != nil {
+		return err
+	}
+	ActiveDataStore.StoreKey(entityUri, wrapped)
+	return nil
+}
+
+// Fetches an entity's private key from the active datastore and unwraps it with the active key vault.
+// The unwrapped key is only held in memory, for as long as the caller needs it to sign.
+func FetchPrivateKey(entityUri references.HashUri) (crypto.Signer, error) {
+	stored, err := ActiveDataStore.FetchKey(entityUri)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := keyvault.ActiveKeyVault.Unwrap(entityUri, stored)
+	if err != nil {
+		return nil, err
+	}
+	privateKey := entities.PrivateKeyFromString(encoded)
+	if privateKey == nil {
+		return nil, errors.New("unable to decode private key for " + entityUri.String())
+	}
+	return privateKey, nil
+}
+
+// Wraps each stored entity key again with the active key vault's current master key, including keys
+// that were stored as plain text, returning the number of keys that were wrapped again.
+//
+// Every key in the datastore is checked, whichever entity it belongs to. Keys that cannot be read or
+// unwrapped, such as those wrapped with a master key that is no longer configured, are left as they are
+// and returned, so that the migration carries on with the other keys.
+func MigrateKeys(ctx context.Context) (int, []references.HashUri, error) {
+	uris, err := ActiveDataStore.FetchKeyUris(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	migrated := 0
+	skipped := make([]references.HashUri, 0)
+	for _, uri := range uris {
+		stored, err := ActiveDataStore.FetchKey(uri)
+		if err != nil {
+			log.ErrorfX(ctx, "Skipping key for %s: %v", uri, err)
+			skipped = append(skipped, uri)
+			continue
+		}
+		if !keyvault.ActiveKeyVault.NeedsRewrap(stored) {
+			continue
+		}
+		encoded, err := keyvault.ActiveKeyVault.Unwrap(uri, stored)
+		if err != nil {
+			log.ErrorfX(ctx, "Skipping key for %s: %v", uri, err)
+			skipped = append(skipped, uri)
+			continue
+		}
+		wrapped, err := keyvault.ActiveKeyVault.Wrap(uri, encoded)
+		if err != nil {
+			return migrated, skipped, err
+		}
+		ActiveDataStore.StoreKey(uri, wrapped)
+		migrated++
+		log.InfofX(ctx, "Migrated key for %s", uri)
+	}
+
+	return migrated, skipped, nil
+}
@@ -0,0 +1,87 @@
+package datastore
+
+import (
+	"context"
+	"testing"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/entities"
+	"silvatek.uk/trustedassertions/internal/keyvault"
+	"silvatek.uk/trustedassertions/internal/references"
+)
+
+func withKeyVault(vault keyvault.KeyVault) func() {
+	previous := keyvault.ActiveKeyVault
+	keyvault.ActiveKeyVault = vault
+	return func() { keyvault.ActiveKeyVault = previous }
+}
+
+func TestWrappedKeys(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+	vault, _ := keyvault.NewEnvelopeVault(keyvault.NewMasterKey())
+	defer withKeyVault(vault)()
+
+	entityUri := CreateEntityWithKey(ctx, "Wrapped")
+	stored, _ := ActiveDataStore.FetchKey(entityUri)
+	if !keyvault.IsWrapped(stored) {
+		t.Errorf("Stored key should be wrapped: %s", stored)
+	}
+
+	if _, err := CreateStatementAndAssertion(ctx, "Signed with a wrapped key", entityUri, assertions.IsTrue, 0.9); err != nil {
+		t.Errorf("Error signing with wrapped key: %v", err)
+	}
+}
+
+func TestMigrateKeys(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	plaintext := CreateEntityWithKey(ctx, "Stored before the vault")
+	withoutKey := entities.Entity{CommonName: "Key held elsewhere"}
+	privateKey, _ := entities.GenerateKey(entities.ECDSA)
+	withoutKey.MakeCertificate(privateKey)
+	ActiveDataStore.Store(ctx, &withoutKey)
+
+	oldMaster, newMaster := keyvault.NewMasterKey(), keyvault.NewMasterKey()
+	oldVault, _ := keyvault.NewEnvelopeVault(oldMaster)
+	restore := withKeyVault(oldVault)
+	previous := CreateEntityWithKey(ctx, "Wrapped with the old master key")
+	restore()
+
+	// Keys can be stored under an untyped URI for an entity that has no record of its own
+	untypedKey, _ := entities.GenerateKey(entities.ECDSA)
+	untyped := references.MakeUri("0123456789abcdef", "")
+	StorePrivateKey(untyped, untypedKey)
+
+	lostVault, _ := keyvault.NewEnvelopeVault(keyvault.NewMasterKey())
+	restore = withKeyVault(lostVault)
+	lost := CreateEntityWithKey(ctx, "Wrapped with a master key that has been lost")
+	restore()
+
+	vault, _ := keyvault.FromConfig(newMaster, oldMaster)
+	defer withKeyVault(vault)()
+
+	migrated, skipped, err := MigrateKeys(ctx)
+	if err != nil || migrated != 3 {
+		t.Errorf("Expected 3 keys to be migrated: %d %v", migrated, err)
+	}
+	if len(skipped) != 1 || skipped[0].Hash() != lost.Hash() {
+		t.Errorf("Expected the key wrapped with the lost master key to be skipped: %v", skipped)
+	}
+	for _, entityUri := range []references.HashUri{plaintext, previous, untyped} {
+		stored, _ := ActiveDataStore.FetchKey(entityUri)
+		if vault.NeedsRewrap(stored) {
+			t.Errorf("Key for %s not wrapped with the current master key", entityUri)
+		}
+		if _, err := FetchPrivateKey(entityUri); err != nil {
+			t.Errorf("Error fetching migrated key for %s: %v", entityUri, err)
+		}
+	}
+
+	if migrated, _, _ := MigrateKeys(ctx); migrated != 0 {
+		t.Errorf("Expected no keys to need migrating again: %d", migrated)
+	}
+}
@@ -3,6 +3,7 @@ package datastore
 import (
 	"context"
 	"errors"
+	"sort"
 	"strings"
 
 	"silvatek.uk/trustedassertions/internal/assertions"
@@ -73,6 +74,16 @@ func (ds *InMemoryDataStore) StoreRef(ctx context.Context, reference refs.Refere
 	ds.refs[targetKey] = refs
 }
 
+func (ds *InMemoryDataStore) FetchRefsOfCategory(ctx context.Context, key HashUri, category string) ([]Reference, error) {
+	refs := make([]Reference, 0)
+	for _, ref := range ds.refs[key.Escaped()] {
+		if ref.Category == category {
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
 func (ds *InMemoryDataStore) FetchInto(key HashUri, item Referenceable) error {
 	record, ok := ds.data[key.Escaped()]
 	if !ok {
@@ -113,6 +124,14 @@ func (ds *InMemoryDataStore) FetchDocument(ctx context.Context, key HashUri) (do
 	return doc, ds.FetchInto(key, &doc)
 }
 
+func (ds *InMemoryDataStore) FetchContent(ctx context.Context, key HashUri) (string, error) {
+	record, ok := ds.data[key.Escaped()]
+	if !ok {
+		return "", errors.New("URI not found: " + key.String())
+	}
+	return record.Content, nil
+}
+
 func (ds *InMemoryDataStore) FetchKey(entityUri HashUri) (string, error) {
 	key, ok := ds.keys[entityUri.Escaped()]
 	if !ok {
@@ -121,6 +140,14 @@ func (ds *InMemoryDataStore) FetchKey(entityUri HashUri) (string, error) {
 	return key, nil
 }
 
+func (ds *InMemoryDataStore) FetchKeyUris(ctx context.Context) ([]HashUri, error) {
+	uris := make([]HashUri, 0, len(ds.keys))
+	for escaped := range ds.keys {
+		uris = append(uris, refs.UnescapeUri(escaped, ""))
+	}
+	return uris, nil
+}
+
 func (ds *InMemoryDataStore) FetchRefs(ctx context.Context, key HashUri) ([]Reference, error) {
 	refs := make([]Reference, 0)
 	result, ok := ds.refs[key.Escaped()]
@@ -137,6 +164,12 @@ func (ds *InMemoryDataStore) FetchRefs(ctx context.Context, key HashUri) ([]Refe
 func (ds *InMemoryDataStore) StoreUser(ctx context.Context, user auth.User) {
 	ds.users[user.Id] = user
 	if user.KeyRefs != nil {
+		// The user's key references replace any stored before, so that references can be removed
+		for key := range ds.krefs {
+			if strings.HasPrefix(key, user.Id+" ") {
+				delete(ds.krefs, key)
+			}
+		}
 		for _, ref := range user.KeyRefs {
 			ds.krefs[ref.UserId+" "+ref.KeyId] = ref
 		}
@@ -175,6 +208,20 @@ func (ds *InMemoryDataStore) Search(ctx context.Context, query string) ([]Search
 	return results, nil
 }
 
+// Returns the URIs of all the stored values of the specified data type, in URI order.
+func (ds *InMemoryDataStore) FetchUris(ctx context.Context, dataType string) ([]HashUri, error) {
+	uris := make([]HashUri, 0)
+	for _, record := range ds.data {
+		if strings.EqualFold(record.DataType, dataType) {
+			uris = append(uris, UriFromString(record.Uri))
+		}
+	}
+	sort.Slice(uris, func(i, j int) bool {
+		return uris[i].String() < uris[j].String()
+	})
+	return uris, nil
+}
+
 func (ds *InMemoryDataStore) Reindex() {
 	// NO-OP
 }
@@ -142,3 +142,41 @@ func storeStatements(content ...string) []HashUri {
 	}
 	return uris
 }
+
+func TestFetchUris(t *testing.T) {
+	InitInMemoryDataStore()
+
+	uris := storeStatements("one", "two")
+	ActiveDataStore.StoreKey(uris[0], "not a record")
+
+	found, err := ActiveDataStore.FetchUris(context.TODO(), "statement")
+	if err != nil {
+		t.Errorf("Error fetching URIs: %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("Unexpected number of URIs: %d", len(found))
+	}
+
+	found, _ = ActiveDataStore.FetchUris(context.TODO(), "entity")
+	if len(found) != 0 {
+		t.Errorf("Unexpected number of entity URIs: %d", len(found))
+	}
+}
+
+func TestFetchContent(t *testing.T) {
+	InitInMemoryDataStore()
+
+	uris := storeStatements("Some raw content")
+
+	content, err := ActiveDataStore.FetchContent(context.TODO(), uris[0])
+	if err != nil {
+		t.Errorf("Error fetching content: %v", err)
+	}
+	if content != "Some raw content" {
+		t.Errorf("Unexpected content: %s", content)
+	}
+
+	if _, err := ActiveDataStore.FetchContent(context.TODO(), MakeUri("nosuchhash", "statement")); err == nil {
+		t.Error("Expected error fetching content of unknown URI")
+	}
+}
@@ -0,0 +1,57 @@
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"silvatek.uk/trustedassertions/internal/assertions"
+)
+
+// Countersigns an assertion that has just been received with an IsTimestamped assertion issued by the
+// timestamp authority at the current time, and stores the timestamp. Does nothing if there is no timestamp
+// authority, or if the assertion is itself one of the authority's timestamps.
+//
+// The timestamp is only referenced from the assertion that it timestamps, so that the authority's
+// entity is not swamped by references from every assertion that the server has received.
+func TimestampAssertion(ctx context.Context, assertion *assertions.Assertion) (*assertions.Assertion, error) {
+	authority := assertions.TimestampAuthority
+	if authority.IsEmpty() || assertions.IsTimestamp(*assertion) {
+		return nil, nil
+	}
+
+	entity, err := ActiveDataStore.FetchEntity(ctx, authority)
+	if err != nil {
+		return nil, fmt.Errorf("fetching timestamp authority %s: %w", authority, err)
+	}
+	privateKey, err := FetchPrivateKey(authority)
+	if err != nil {
+		return nil, fmt.Errorf("fetching key of timestamp authority %s: %w", authority, err)
+	}
+
+	timestamp := assertions.NewAssertion(assertions.IsTimestamped)
+	timestamp.Subject = assertion.Uri().String()
+	timestamp.Issuer = entity.Uri().String()
+	timestamp.Confidence = 1.0
+	timestamp.IssuedAt = jwt.NewNumericDate(time.Now())
+	timestamp.NotBefore = timestamp.IssuedAt
+	if err := entity.CheckValidAt(timestamp.IssuedAt.Time); err != nil {
+		return nil, err
+	}
+
+	timestamp.SetSummary(assertions.SummariseAssertion(ctx, timestamp, nil, ActiveDataStore))
+	timestamp.MakeJwt(privateKey)
+	ActiveDataStore.Store(ctx, &timestamp)
+	CreateReferenceWithSummary(ctx, timestamp.Uri(), assertion.Uri())
+
+	return &timestamp, nil
+}
+
+// Timestamps an assertion that has just been stored, logging rather than returning any error, as the
+// assertion is valid whether or not it can be timestamped.
+func timestampReceived(ctx context.Context, assertion *assertions.Assertion) {
+	if _, err := TimestampAssertion(ctx, assertion); err != nil {
+		log.ErrorfX(ctx, "Error timestamping assertion %s: %v", assertion.Uri(), err)
+	}
+}
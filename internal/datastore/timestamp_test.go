@@ -0,0 +1,136 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/entities"
+	"silvatek.uk/trustedassertions/internal/references"
+)
+
+func TestTimestampAssertions(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+
+	authority := CreateEntityWithKey(ctx, "Timestamp authority")
+	defer assertions.UseTimestampAuthority(authority)()
+
+	signerKey, _ := entities.GenerateKey(entities.DefaultKeyAlgorithm)
+	signer := entities.Entity{CommonName: "Offline signer", Issued: time.Now().AddDate(0, -1, 0)}
+	signer.MakeCertificate(signerKey)
+	ActiveDataStore.Store(ctx, &signer)
+	StorePrivateKey(signer.Uri(), signerKey)
+	statement := CreateStatement(ctx, "Time will tell")
+
+	created, err := CreateAssertion(ctx, statement, signer.Uri(), assertions.IsTrue, 0.9, signerKey)
+	if err != nil {
+		t.Fatalf("Error creating assertion: %v", err)
+	}
+	timestamp, found := assertions.TimestampOf(ctx, ActiveDataStore, *created)
+	if !found || timestamp.Subject != created.Uri().String() || !references.UriFromString(timestamp.Issuer).Equals(authority) {
+		t.Fatalf("Created assertion should be timestamped by the authority: %+v", timestamp)
+	}
+	if _, found := assertions.TimestampOf(ctx, ActiveDataStore, timestamp); found {
+		t.Error("Timestamps should not themselves be timestamped")
+	}
+	if refs, _ := ActiveDataStore.FetchRefs(ctx, authority); len(refs) != 0 {
+		t.Errorf("Timestamps should not be referenced from the authority: %v", refs)
+	}
+
+	// Timestamps from anyone other than the authority are rejected, and ignored if they are stored anyway
+	if _, err := CreateAssertion(ctx, created.Uri(), signer.Uri(), assertions.IsTimestamped, 1.0, signerKey); !errors.Is(err, ErrInvalidTimestamp) {
+		t.Errorf("Expected timestamp from the signer to be rejected: %v", err)
+	}
+	forged := assertions.NewAssertion(assertions.IsTimestamped)
+	forged.Subject = created.Uri().String()
+	forged.Issuer = signer.Uri().String()
+	forged.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	forged.MakeJwt(signerKey)
+	ActiveDataStore.Store(ctx, &forged)
+	CreateReferences(ctx, &forged)
+	if other, _ := assertions.TimestampOf(ctx, ActiveDataStore, *created); !other.Uri().Equals(timestamp.Uri()) {
+		t.Errorf("Only the authority's timestamp should be used: %s", other.Issuer)
+	}
+
+	// A backdated assertion is taken to have been issued when it was received
+	claims := assertions.NewAssertion(assertions.IsFalse)
+	claims.Subject = statement.String()
+	claims.Issuer = signer.Uri().String()
+	claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-48 * time.Hour))
+	claims.MakeJwt(signerKey)
+	submitted, err := StoreSignedAssertion(ctx, claims.Content())
+	if err != nil {
+		t.Fatalf("Error storing signed assertion: %v", err)
+	}
+	fetched, _ := ActiveDataStore.FetchAssertion(ctx, submitted.Uri())
+	if _, found := fetched.ApplyTimestamp(ctx, ActiveDataStore); !found {
+		t.Fatal("Submitted assertion should be timestamped")
+	}
+	if time.Since(fetched.IssueTime()) > time.Minute || fetched.TimestampDelay() < 47*time.Hour {
+		t.Errorf("Backdated assertion should be dated when it was received: %v %v", fetched.IssueTime(), fetched.TimestampDelay())
+	}
+
+	// Once its key is compromised, the signer cannot backdate assertions to before the compromise
+	compromise := assertions.NewAssertion(assertions.IsCompromised)
+	compromise.Subject = signer.Uri().String()
+	compromise.Issuer = signer.Uri().String()
+	compromise.Since = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	if _, err := CreateSignedAssertion(ctx, compromise, signerKey); err != nil {
+		t.Fatalf("Error creating compromise: %v", err)
+	}
+	if _, err := ActiveDataStore.FetchAssertion(ctx, submitted.Uri()); !errors.Is(err, assertions.ErrCompromised) {
+		t.Errorf("Assertion received after the compromise should be rejected: %v", err)
+	}
+	claims.Confidence = 0.5
+	claims.MakeJwt(signerKey)
+	if _, err := StoreSignedAssertion(ctx, claims.Content()); !errors.Is(err, assertions.ErrCompromised) {
+		t.Errorf("Expected compromise error for a backdated submission: %v", err)
+	}
+}
+
+// Counts the assertions fetched through it, to check how many referring assertions are read.
+type countingResolver struct {
+	DataStore
+	fetched int
+}
+
+func (r *countingResolver) FetchAssertion(ctx context.Context, uri references.HashUri) (assertions.Assertion, error) {
+	r.fetched++
+	return r.DataStore.FetchAssertion(ctx, uri)
+}
+
+func TestAnnotateAssertion(t *testing.T) {
+	ctx := context.Background()
+	InitInMemoryDataStore()
+	assertions.PublicKeyResolver = ActiveDataStore
+	defer assertions.UseTimestampAuthority(CreateEntityWithKey(ctx, "Timestamp authority"))()
+
+	issuer := CreateEntityWithKey(ctx, "Issuer")
+	issuerKey, _ := FetchPrivateKey(issuer)
+	original, err := CreateAssertion(ctx, CreateStatement(ctx, "Second thoughts"), issuer, assertions.IsTrue, 0.9, issuerKey)
+	if err != nil {
+		t.Fatalf("Error creating assertion: %v", err)
+	}
+	retraction, _ := CreateAssertion(ctx, original.Uri(), issuer, assertions.Retracts, 1.0, issuerKey)
+	// Assertions citing the original as their basis also refer to it, but cannot annotate it
+	CreateAssertion(ctx, CreateStatement(ctx, "Third thoughts"), issuer, assertions.IsTrue, 0.5, issuerKey, original.Uri())
+
+	resolver := &countingResolver{DataStore: ActiveDataStore}
+	fetched, _ := ActiveDataStore.FetchAssertion(ctx, original.Uri())
+	annotations := fetched.Annotate(ctx, resolver)
+
+	if annotations.Retraction == nil || !annotations.Retraction.Uri().Equals(retraction.Uri()) {
+		t.Errorf("Retraction not found for retracted assertion: %+v", annotations.Retraction)
+	}
+	if annotations.Timestamp == nil || fetched.TimestampedAt().IsZero() {
+		t.Errorf("Timestamp not applied to annotated assertion: %+v", annotations.Timestamp)
+	}
+	if resolver.fetched != 2 {
+		t.Errorf("Only the retraction and timestamp should be fetched, not %d assertions", resolver.fetched)
+	}
+}
@@ -17,10 +17,11 @@ type Document struct {
 }
 
 type MetaData struct {
-	XMLName  xml.Name `xml:"metadata"`
-	Author   Author   `xml:"author,omitempty"`
-	Title    string   `xml:"title,omitempty"`
-	Keywords string   `xml:"keywords,omitempty"`
+	XMLName   xml.Name `xml:"metadata"`
+	Author    Author   `xml:"author,omitempty"`
+	Title     string   `xml:"title,omitempty"`
+	Keywords  string   `xml:"keywords,omitempty"`
+	Signature string   `xml:"signature,omitempty"` // The author's detached signature of the canonical XML
 }
 
 type Author struct {
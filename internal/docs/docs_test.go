@@ -2,11 +2,15 @@ package docs
 
 import (
 	"bytes"
+	"errors"
+	"math/big"
 	"strings"
 	"testing"
 	"text/template"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"silvatek.uk/trustedassertions/internal/entities"
 	"silvatek.uk/trustedassertions/internal/search"
 )
 
@@ -58,3 +62,51 @@ func wordsContains(wordList []string, words ...string) bool {
 	}
 	return matches == len(words)
 }
+
+func TestSignDocument(t *testing.T) {
+	for _, algorithm := range entities.KeyAlgorithms() {
+		privateKey, _ := entities.GenerateKey(algorithm)
+		author := entities.NewEntity("Author", *big.NewInt(1234))
+		author.MakeCertificate(privateKey)
+
+		doc, _ := LoadDocument("../../testdata/documents/testdoc1.xml")
+		doc.Metadata.Author = Author{}
+		if err := doc.VerifySignature(author); !errors.Is(err, ErrUnsigned) {
+			t.Errorf("Expected unsigned error: %v", err)
+		}
+		if err := doc.Sign(author, privateKey); err != nil {
+			t.Fatalf("Error signing document with %s key: %v", algorithm, err)
+		}
+		if doc.Metadata.Author.Entity != author.Uri().String() || doc.Metadata.Author.Name != "Author" {
+			t.Errorf("Signer should become the author: %+v", doc.Metadata.Author)
+		}
+
+		// The signature is verified against the stored content
+		stored, err := MakeDocument(doc.Content())
+		if err != nil {
+			t.Fatalf("Error parsing signed document: %v", err)
+		}
+		if err := stored.VerifySignature(author); err != nil {
+			t.Errorf("Error verifying %s signed document: %v", algorithm, err)
+		}
+		if time.Since(stored.SignedAt()) > time.Minute {
+			t.Errorf("Signed document should record when it was signed: %v", stored.SignedAt())
+		}
+
+		stored.Sections[0].Paragraphs[0].Spans[0].Body = "What do we know about the universe?!"
+		if err := stored.VerifySignature(author); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Altered %s signed document should not verify: %v", algorithm, err)
+		}
+
+		otherKey, _ := entities.GenerateKey(algorithm)
+		other := entities.NewEntity("Other", *big.NewInt(5678))
+		other.MakeCertificate(otherKey)
+		if err := doc.VerifySignature(other); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Document should not verify against another entity: %v", err)
+		}
+		doc.Metadata.Author.Entity = other.Uri().String()
+		if err := doc.VerifySignature(other); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Document naming another author should not verify: %v", err)
+		}
+	}
+}
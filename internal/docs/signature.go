@@ -0,0 +1,135 @@
+package docs
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"silvatek.uk/trustedassertions/internal/entities"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// A document is signed by its author entity with a detached JWS (RFC 7515 appendix F), whose payload
+// is the canonical XML of the document and is left out of the signature. The signature is kept in the
+// document's metadata, and the canonical XML is the document marshalled without it, so that the signature
+// covers everything else in the document, including the URIs of the assertions it refers to.
+
+// Returned when verifying a document that has no author signature.
+var ErrUnsigned = errors.New("document has no author signature")
+
+// Returned when verifying a document whose author signature does not match the document or its author.
+var ErrInvalidSignature = errors.New("document author signature not valid")
+
+type signatureHeader struct {
+	Algorithm string `json:"alg"`
+	KeyId     string `json:"kid"`           // The URI of the author entity
+	IssuedAt  int64  `json:"iat,omitempty"` // When the document was signed, in seconds since the epoch as for a JWT
+}
+
+// Returns the canonical XML of the document, which is the document marshalled without its signature.
+func (d Document) CanonicalXml() string {
+	d.Metadata.Signature = ""
+	data, _ := xml.Marshal(d)
+	return string(data)
+}
+
+// Signs the canonical XML of the document with the private key of the author entity, which becomes
+// the author of the document, and updates the content of the document to include the signature.
+// The signing time is recorded in the protected header of the signature, so it is covered by the signature.
+func (d *Document) Sign(author entities.Entity, privateKey crypto.Signer) error {
+	method, ok := entities.SigningMethodOf(entities.AlgorithmOf(privateKey))
+	if !ok {
+		return fmt.Errorf("unsupported key type for signing document: %T", privateKey)
+	}
+
+	d.Metadata.Author.Entity = author.Uri().String()
+	if d.Metadata.Author.Name == "" {
+		d.Metadata.Author.Name = author.CommonName
+	}
+
+	header, err := json.Marshal(signatureHeader{Algorithm: method.Alg(), KeyId: author.Uri().String(), IssuedAt: time.Now().Unix()})
+	if err != nil {
+		return err
+	}
+	encodedHeader := base64.RawURLEncoding.EncodeToString(header)
+	signature, err := method.Sign(d.signingString(encodedHeader), privateKey)
+	if err != nil {
+		return err
+	}
+
+	d.Metadata.Signature = encodedHeader + ".." + base64.RawURLEncoding.EncodeToString(signature)
+	d.UpdateContent()
+	d.uri = refs.HashUri{}
+	return nil
+}
+
+// Whether the document has an author signature, which may not be valid.
+func (d Document) IsSigned() bool {
+	return d.Metadata.Signature != ""
+}
+
+// Verifies that the document was signed by its author entity, whose certificate is supplied by the caller.
+func (d Document) VerifySignature(author entities.Entity) error {
+	if !d.IsSigned() {
+		return ErrUnsigned
+	}
+	if refs.UriFromString(d.Metadata.Author.Entity).Hash() != author.Uri().Hash() {
+		return fmt.Errorf("%w: author is %s, not %s", ErrInvalidSignature, d.Metadata.Author.Entity, author.Uri())
+	}
+
+	encodedHeader, header, signature, err := d.signatureParts()
+	if err != nil {
+		return err
+	}
+
+	method, ok := entities.SigningMethodOf(author.KeyAlgorithm())
+	if !ok || header.Algorithm != method.Alg() {
+		return fmt.Errorf("%w: algorithm %s does not match the author's key", ErrInvalidSignature, header.Algorithm)
+	}
+	if err := method.Verify(d.signingString(encodedHeader), signature, author.PublicKey); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+// Returns the time at which the author signed the document, or the zero time if the document is not
+// signed or was signed without recording the time. The time is only trustworthy once the signature
+// has been verified.
+func (d Document) SignedAt() time.Time {
+	_, header, _, err := d.signatureParts()
+	if err != nil || header.IssuedAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(header.IssuedAt, 0)
+}
+
+// Splits the detached JWS of the document into its encoded header, the decoded header and the signature.
+func (d Document) signatureParts() (string, signatureHeader, []byte, error) {
+	var header signatureHeader
+	encodedHeader, encodedSignature, found := strings.Cut(strings.TrimSpace(d.Metadata.Signature), "..")
+	if !found || strings.Contains(encodedSignature, ".") {
+		return "", header, nil, fmt.Errorf("%w: not a detached JWS", ErrInvalidSignature)
+	}
+	headerJson, err := base64.RawURLEncoding.DecodeString(encodedHeader)
+	if err != nil {
+		return "", header, nil, fmt.Errorf("%w: header: %v", ErrInvalidSignature, err)
+	}
+	if err := json.Unmarshal(headerJson, &header); err != nil {
+		return "", header, nil, fmt.Errorf("%w: header: %v", ErrInvalidSignature, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return "", header, nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return encodedHeader, header, signature, nil
+}
+
+// Returns the JWS signing input for the canonical XML of the document.
+func (d Document) signingString(encodedHeader string) string {
+	return encodedHeader + "." + base64.RawURLEncoding.EncodeToString([]byte(d.CanonicalXml()))
+}
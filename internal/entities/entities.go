@@ -1,11 +1,10 @@
 package entities
 
 import (
+	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
-	"encoding/base64"
 	"encoding/pem"
 	"math/big"
 	"time"
@@ -16,11 +15,13 @@ import (
 
 type Entity struct {
 	SerialNum   big.Int
-	CommonName  string         `json:"name"`
-	Certificate string         `json:"cert"`
-	uri         refs.HashUri   `json:"-"`
-	Issued      time.Time      `json:"-"`
-	PublicKey   *rsa.PublicKey `json:"-"`
+	CommonName  string           `json:"name"`
+	Certificate string           `json:"cert"`
+	uri         refs.HashUri     `json:"-"`
+	Issued      time.Time        `json:"-"` // The start of the certificate's validity period
+	Expires     time.Time        `json:"-"` // The end of the certificate's validity period
+	PublicKey   crypto.PublicKey `json:"-"` // An RSA, ECDSA P-256 or Ed25519 public key
+	Parent      refs.HashUri     `json:"-"` // The organisation entity that issued the certificate, empty if self-signed
 }
 
 var log = logging.GetLogger("entities")
@@ -76,32 +77,61 @@ func (e *Entity) TextContent() string {
 	return e.CommonName
 }
 
+// Returns the organisation that issued the entity's certificate, if it was not self-signed.
 func (e *Entity) References() []refs.HashUri {
-	return []refs.HashUri{}
+	if e.Parent.IsEmpty() {
+		return []refs.HashUri{}
+	}
+	return []refs.HashUri{e.Parent}
+}
+
+// Returns the algorithm of the entity's signing key.
+func (e *Entity) KeyAlgorithm() KeyAlgorithm {
+	return AlgorithmOf(e.PublicKey)
+}
+
+// Makes a self-signed certificate for the entity, which can use an RSA, ECDSA P-256 or Ed25519 private key.
+func (e *Entity) MakeCertificate(privateKey crypto.Signer) {
+	algorithm := AlgorithmOf(privateKey)
+	if algorithm == "" {
+		log.Errorf("Unsupported key type for entity certificate: %T", privateKey)
+		return
+	}
+	e.PublicKey = privateKey.Public()
+
+	template := e.certificateTemplate(algorithm)
+	cert, err := x509.CreateCertificate(rand.Reader, &template, &template, e.PublicKey, privateKey)
+	if err != nil {
+		log.Errorf("Error creating entity certificate: %v", err)
+		return
+	}
+	e.setCertificate(cert)
 }
 
-func (e *Entity) MakeCertificate(privateKey *rsa.PrivateKey) {
+// Returns the template for a certificate for the entity, which is signed using the specified algorithm.
+// All entities can issue certificates for members, so every certificate is marked as a CA.
+func (e *Entity) certificateTemplate(signingAlgorithm KeyAlgorithm) x509.Certificate {
 	if !e.HasSerialNum() {
 		e.AssignSerialNum()
 	}
-	e.PublicKey = &privateKey.PublicKey
-
-	template := x509.Certificate{
+	if e.Issued.IsZero() {
+		e.Issued = time.Now()
+	}
+	e.Expires = e.Issued.Add(CertificateLifetime)
+	return x509.Certificate{
 		SerialNumber:          &e.SerialNum,
 		IsCA:                  true,
 		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
 		Subject:               pkix.Name{CommonName: e.CommonName},
-		SignatureAlgorithm:    x509.SHA256WithRSA,
+		SignatureAlgorithm:    signingAlgorithm.signatureAlgorithm(),
 		NotBefore:             e.Issued,
-		NotAfter:              e.Issued.Add(time.Hour * 24 * 365 * 2),
+		NotAfter:              e.Expires,
 		BasicConstraintsValid: true,
 	}
-	cert, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
-	if err != nil {
-		log.Errorf("Error creating entity certificate: %v", err)
-		return
-	}
-	b := pem.Block{Type: "CERTIFICATE", Bytes: cert}
+}
+
+func (e *Entity) setCertificate(der []byte) {
+	b := pem.Block{Type: "CERTIFICATE", Bytes: der}
 	e.Certificate = string(pem.EncodeToMemory(&b))
 }
 
@@ -114,13 +144,21 @@ func (e *Entity) ParseContent(content string) error {
 	if err != nil {
 		return err
 	} else {
-		e.SerialNum = *cert.SerialNumber
-		e.CommonName = cert.Subject.CommonName
-		e.PublicKey = cert.PublicKey.(*rsa.PublicKey)
+		e.setFromCertificate(cert)
 		return nil
 	}
 }
 
+// Sets the fields of the entity that are held in its certificate.
+func (e *Entity) setFromCertificate(cert *x509.Certificate) {
+	e.SerialNum = *cert.SerialNumber
+	e.CommonName = cert.Subject.CommonName
+	e.PublicKey = cert.PublicKey
+	e.Issued = cert.NotBefore
+	e.Expires = cert.NotAfter
+	e.Parent = parentOf(cert)
+}
+
 func ParseCertificate(content string) Entity {
 	entity := NewEntity("{unknown}", *big.NewInt(0))
 
@@ -132,23 +170,8 @@ func ParseCertificate(content string) Entity {
 	if err != nil {
 		log.Errorf("Error parsing X509 certificate: %v", err)
 	} else {
-		entity.SerialNum = *cert.SerialNumber
-		entity.CommonName = cert.Subject.CommonName
-		entity.PublicKey = cert.PublicKey.(*rsa.PublicKey)
+		entity.setFromCertificate(cert)
 	}
 
 	return entity
 }
-
-func PrivateKeyToString(prvKey *rsa.PrivateKey) string {
-	return base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PrivateKey(prvKey))
-}
-
-func PrivateKeyFromString(base64encoded string) *rsa.PrivateKey {
-	bytes, _ := base64.StdEncoding.DecodeString(base64encoded)
-	privateKey, err := x509.ParsePKCS1PrivateKey(bytes)
-	if err != nil {
-		log.Errorf("Error decoding key")
-	}
-	return privateKey
-}
@@ -100,7 +100,7 @@ func TestPrivateKeyToFromString(t *testing.T) {
 
 	keyStr := PrivateKeyToString(key1)
 
-	key2 := PrivateKeyFromString(keyStr)
+	key2 := PrivateKeyFromString(keyStr).(*rsa.PrivateKey)
 
 	if key2.N.Cmp(key1.N) != 0 {
 		t.Errorf("Key does not match after round trip: %v != %v", key2.N, key1.N)
@@ -0,0 +1,157 @@
+package entities
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyAlgorithm is the algorithm of an entity's signing key.
+type KeyAlgorithm string
+
+const (
+	RSA     KeyAlgorithm = "RSA"     // RSA-2048, signing with SHA-256 (RS256)
+	ECDSA   KeyAlgorithm = "P-256"   // ECDSA on the P-256 curve, signing with SHA-256 (ES256)
+	Ed25519 KeyAlgorithm = "Ed25519" // Ed25519 (EdDSA)
+)
+
+// The algorithm used for new keys when no other has been chosen.
+var DefaultKeyAlgorithm = RSA
+
+// Returns the algorithms that can be chosen for new signing keys.
+func KeyAlgorithms() []KeyAlgorithm {
+	return []KeyAlgorithm{RSA, ECDSA, Ed25519}
+}
+
+// Returns the key algorithm with the specified name, ignoring case.
+func KeyAlgorithmOf(name string) (KeyAlgorithm, error) {
+	for _, algorithm := range KeyAlgorithms() {
+		if strings.EqualFold(name, string(algorithm)) {
+			return algorithm, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported key algorithm: %s", name)
+}
+
+func (ka KeyAlgorithm) String() string {
+	return string(ka)
+}
+
+// Returns a description of the algorithm for display.
+func (ka KeyAlgorithm) Description() string {
+	switch ka {
+	case RSA:
+		return "RSA 2048 (RS256)"
+	case ECDSA:
+		return "ECDSA P-256 (ES256)"
+	case Ed25519:
+		return "Ed25519 (EdDSA)"
+	default:
+		return "Unknown"
+	}
+}
+
+// Returns the algorithm for signing certificates with keys of this algorithm.
+func (ka KeyAlgorithm) signatureAlgorithm() x509.SignatureAlgorithm {
+	switch ka {
+	case RSA:
+		return x509.SHA256WithRSA
+	case ECDSA:
+		return x509.ECDSAWithSHA256
+	case Ed25519:
+		return x509.PureEd25519
+	default:
+		return x509.UnknownSignatureAlgorithm
+	}
+}
+
+// Returns the JWT signing method for keys of the specified algorithm, which is used to sign both
+// assertions and documents.
+func SigningMethodOf(algorithm KeyAlgorithm) (jwt.SigningMethod, bool) {
+	switch algorithm {
+	case RSA:
+		return jwt.SigningMethodRS256, true
+	case ECDSA:
+		return jwt.SigningMethodES256, true
+	case Ed25519:
+		return jwt.SigningMethodEdDSA, true
+	default:
+		return nil, false
+	}
+}
+
+// Generates a new private key using the specified algorithm.
+func GenerateKey(algorithm KeyAlgorithm) (crypto.Signer, error) {
+	switch algorithm {
+	case RSA:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case ECDSA:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case Ed25519:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm: %s", algorithm)
+	}
+}
+
+// Returns the algorithm of a public or private key, or an empty string if it is not a supported kind of key.
+func AlgorithmOf(key any) KeyAlgorithm {
+	switch k := key.(type) {
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return RSA
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return ECDSA
+		}
+	case *ecdsa.PrivateKey:
+		if k.Curve == elliptic.P256() {
+			return ECDSA
+		}
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return Ed25519
+	}
+	return ""
+}
+
+// Encodes a private key as base64. RSA keys are encoded in PKCS #1 form, as they always have been,
+// and other keys in PKCS #8 form.
+func PrivateKeyToString(prvKey crypto.Signer) string {
+	if rsaKey, ok := prvKey.(*rsa.PrivateKey); ok {
+		return base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PrivateKey(rsaKey))
+	}
+	bytes, err := x509.MarshalPKCS8PrivateKey(prvKey)
+	if err != nil {
+		log.Errorf("Error encoding key: %v", err)
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(bytes)
+}
+
+// Decodes a private key encoded by PrivateKeyToString, returning nil if it cannot be decoded.
+func PrivateKeyFromString(base64encoded string) crypto.Signer {
+	bytes, _ := base64.StdEncoding.DecodeString(base64encoded)
+	if rsaKey, err := x509.ParsePKCS1PrivateKey(bytes); err == nil {
+		return rsaKey
+	}
+	key, err := x509.ParsePKCS8PrivateKey(bytes)
+	if err != nil {
+		log.Errorf("Error decoding key")
+		return nil
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok || AlgorithmOf(signer) == "" {
+		log.Errorf("Unsupported key type: %T", key)
+		return nil
+	}
+	return signer
+}
@@ -0,0 +1,68 @@
+package entities
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestKeyAlgorithms(t *testing.T) {
+	for _, algorithm := range KeyAlgorithms() {
+		privateKey, err := GenerateKey(algorithm)
+		if err != nil {
+			t.Fatalf("Error generating %s key: %v", algorithm, err)
+		}
+
+		entity := NewEntity("Tester "+algorithm.String(), *big.NewInt(0))
+		entity.MakeCertificate(privateKey)
+		if entity.Certificate == "" {
+			t.Fatalf("No certificate made for %s key", algorithm)
+		}
+
+		parsed := ParseCertificate(entity.Certificate)
+		if parsed.KeyAlgorithm() != algorithm || parsed.CommonName != entity.CommonName {
+			t.Errorf("Unexpected certificate for %s key: %s %s", algorithm, parsed.KeyAlgorithm(), parsed.CommonName)
+		}
+
+		decoded := PrivateKeyFromString(PrivateKeyToString(privateKey))
+		if decoded == nil || AlgorithmOf(decoded) != algorithm {
+			t.Errorf("%s key did not survive round trip through string", algorithm)
+		}
+
+		method, ok := SigningMethodOf(algorithm)
+		if !ok {
+			t.Fatalf("No signing method for %s key", algorithm)
+		}
+		signature, err := method.Sign("signed", privateKey)
+		if err != nil || method.Verify("signed", signature, parsed.PublicKey) != nil {
+			t.Errorf("%s key did not sign and verify with %s: %v", algorithm, method.Alg(), err)
+		}
+	}
+}
+
+func TestKeyAlgorithmOf(t *testing.T) {
+	if algorithm, err := KeyAlgorithmOf("ed25519"); err != nil || algorithm != Ed25519 {
+		t.Errorf("Unexpected algorithm: %s %v", algorithm, err)
+	}
+	if _, err := KeyAlgorithmOf("DSA"); err == nil {
+		t.Error("Expected error for unsupported algorithm")
+	}
+	if _, ok := SigningMethodOf("DSA"); ok {
+		t.Error("Expected no signing method for unsupported algorithm")
+	}
+	if _, err := GenerateKey("DSA"); err == nil {
+		t.Error("Expected error generating key for unsupported algorithm")
+	}
+
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if AlgorithmOf(p384) != "" {
+		t.Error("Only P-256 ECDSA keys should be supported")
+	}
+	entity := NewEntity("Tester", *big.NewInt(0))
+	entity.MakeCertificate(p384)
+	if entity.Certificate != "" {
+		t.Error("Certificate should not be made with an unsupported key")
+	}
+}
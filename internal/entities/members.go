@@ -0,0 +1,84 @@
+package entities
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// Returned when an entity's certificate was not issued by the organisation that it names as its parent.
+var ErrNotIssuedBy = errors.New("certificate not issued by parent entity")
+
+// Makes a certificate for the entity as a member of an organisation, such as a person or department,
+// signed with the organisation's private key.
+//
+// The certificate names the organisation through its issuing certificate URL, which is the hash URI
+// of the organisation's own certificate.
+func (e *Entity) MakeMemberCertificate(privateKey crypto.Signer, parent Entity, parentKey crypto.Signer) error {
+	if AlgorithmOf(privateKey) == "" || AlgorithmOf(parentKey) == "" {
+		return fmt.Errorf("unsupported key types for member certificate: %T, %T", privateKey, parentKey)
+	}
+	parentCert, err := parent.x509Certificate()
+	if err != nil {
+		return err
+	}
+	e.PublicKey = privateKey.Public()
+
+	template := e.certificateTemplate(AlgorithmOf(parentKey))
+	template.IssuingCertificateURL = []string{parent.Uri().String()}
+	cert, err := x509.CreateCertificate(rand.Reader, &template, parentCert, e.PublicKey, parentKey)
+	if err != nil {
+		return err
+	}
+	e.setCertificate(cert)
+	e.Parent = parent.Uri()
+	return nil
+}
+
+// Whether the entity's certificate was signed with its own key, rather than issued by an organisation.
+func (e *Entity) IsSelfSigned() bool {
+	return e.Parent.IsEmpty()
+}
+
+// Checks that the entity's certificate names the parent as its issuer and was signed with the parent's key.
+func (e *Entity) VerifyIssuedBy(parent Entity) error {
+	if e.Parent.IsEmpty() || e.Parent.Hash() != parent.Uri().Hash() {
+		return fmt.Errorf("%w: %s does not name %s", ErrNotIssuedBy, e.CommonName, parent.CommonName)
+	}
+	cert, err := e.x509Certificate()
+	if err != nil {
+		return err
+	}
+	parentCert, err := parent.x509Certificate()
+	if err != nil {
+		return err
+	}
+	if err := cert.CheckSignatureFrom(parentCert); err != nil {
+		return fmt.Errorf("%w: %v", ErrNotIssuedBy, err)
+	}
+	return nil
+}
+
+func (e *Entity) x509Certificate() (*x509.Certificate, error) {
+	p, _ := pem.Decode([]byte(e.Certificate))
+	if p == nil {
+		return nil, errors.New("entity has no certificate")
+	}
+	return x509.ParseCertificate(p.Bytes)
+}
+
+// Returns the entity that issued a certificate, from the hash URI in its issuing certificate URLs.
+func parentOf(cert *x509.Certificate) refs.HashUri {
+	for _, url := range cert.IssuingCertificateURL {
+		if strings.HasPrefix(url, "hash://") {
+			return refs.MakeUri(refs.UriFromString(url).Hash(), "entity")
+		}
+	}
+	return refs.HashUri{}
+}
@@ -0,0 +1,46 @@
+package entities
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemberCertificate(t *testing.T) {
+	orgKey, _ := GenerateKey(ECDSA)
+	organisation := Entity{CommonName: "Organisation"}
+	organisation.MakeCertificate(orgKey)
+
+	memberKey, _ := GenerateKey(Ed25519)
+	member := Entity{CommonName: "Member"}
+	if err := member.MakeMemberCertificate(memberKey, organisation, orgKey); err != nil {
+		t.Fatalf("Error making member certificate: %v", err)
+	}
+
+	parsed := ParseCertificate(member.Certificate)
+	if parsed.IsSelfSigned() || !parsed.Parent.Equals(organisation.Uri()) {
+		t.Errorf("Member certificate does not name the organisation: %s", parsed.Parent)
+	}
+	if len(parsed.References()) != 1 || parsed.KeyAlgorithm() != Ed25519 {
+		t.Errorf("Unexpected member references or key: %v %s", parsed.References(), parsed.KeyAlgorithm())
+	}
+	if err := parsed.VerifyIssuedBy(organisation); err != nil {
+		t.Errorf("Member certificate not verified: %v", err)
+	}
+	if !organisation.IsSelfSigned() || len(organisation.References()) != 0 {
+		t.Error("Organisation should be self-signed")
+	}
+
+	// An impostor with the same name cannot have issued the certificate
+	impostorKey, _ := GenerateKey(ECDSA)
+	impostor := Entity{CommonName: "Organisation"}
+	impostor.MakeCertificate(impostorKey)
+	if err := parsed.VerifyIssuedBy(impostor); !errors.Is(err, ErrNotIssuedBy) {
+		t.Errorf("Expected error verifying against another organisation: %v", err)
+	}
+
+	// Nor can a certificate be issued with a key that does not match the organisation
+	forged := Entity{CommonName: "Forged"}
+	if err := forged.MakeMemberCertificate(memberKey, organisation, impostorKey); err == nil {
+		t.Error("Expected error issuing certificate with the wrong organisation key")
+	}
+}
@@ -0,0 +1,34 @@
+package entities
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+)
+
+// Makes a new entity with the same name as this one and a certificate for a new key, which is
+// self-signed or issued by the same organisation. The organisation's key is only needed when
+// rotating the key of a member of an organisation.
+func (e *Entity) Rotate(newKey crypto.Signer, parent *Entity, parentKey crypto.Signer) (Entity, error) {
+	if e.HasSameKey(Entity{PublicKey: newKey.Public()}) {
+		return Entity{}, errors.New("new key is the same as the entity's current key")
+	}
+	return e.successor(newKey, parent, parentKey)
+}
+
+// Makes a new certificate with the same name as this entity, issued in the same way.
+func (e *Entity) successor(privateKey crypto.Signer, parent *Entity, parentKey crypto.Signer) (Entity, error) {
+	next := Entity{CommonName: e.CommonName}
+	if e.IsSelfSigned() {
+		next.MakeCertificate(privateKey)
+		if next.Certificate == "" {
+			return Entity{}, fmt.Errorf("unable to make certificate for %s", e.CommonName)
+		}
+		return next, nil
+	}
+
+	if parent == nil || parent.Uri().Hash() != e.Parent.Hash() {
+		return Entity{}, fmt.Errorf("%w: %s must be issued by %s", ErrNotIssuedBy, e.CommonName, e.Parent)
+	}
+	return next, next.MakeMemberCertificate(privateKey, *parent, parentKey)
+}
@@ -0,0 +1,63 @@
+package entities
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Returned when an entity's certificate was not valid at the time it was used.
+var ErrCertificateNotValid = errors.New("entity certificate not valid")
+
+// How long new entity certificates are valid for.
+var CertificateLifetime = time.Hour * 24 * 365 * 2
+
+// How long before a certificate expires that its entity should be renewed.
+var RenewalPeriod = time.Hour * 24 * 30
+
+// Whether the entity's certificate was valid at the specified time.
+func (e *Entity) ValidAt(t time.Time) bool {
+	return !t.Before(e.Issued) && !t.After(e.Expires)
+}
+
+// Checks that the entity's certificate was valid at the specified time.
+func (e *Entity) CheckValidAt(t time.Time) error {
+	if !e.ValidAt(t) {
+		return fmt.Errorf("%w: %s is valid from %v until %v, not at %v", ErrCertificateNotValid,
+			e.CommonName, e.Issued.Format(time.RFC3339), e.Expires.Format(time.RFC3339), t.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Whether the entity's certificate has expired.
+func (e *Entity) IsExpired() bool {
+	return time.Now().After(e.Expires)
+}
+
+// Whether the entity's certificate is still valid but will expire within the specified period.
+func (e *Entity) ExpiresWithin(period time.Duration) bool {
+	return !e.IsExpired() && time.Now().Add(period).After(e.Expires)
+}
+
+// Whether the entity should be renewed, because its certificate has expired or will soon.
+func (e *Entity) NeedsRenewal() bool {
+	return e.IsExpired() || e.ExpiresWithin(RenewalPeriod)
+}
+
+// Makes a new entity with the same name as this one and a new certificate for the same key, which
+// is self-signed or issued by the same organisation. The organisation's key is only needed when
+// renewing a member of an organisation.
+func (e *Entity) Renew(privateKey crypto.Signer, parent *Entity, parentKey crypto.Signer) (Entity, error) {
+	if !e.HasSameKey(Entity{PublicKey: privateKey.Public()}) {
+		return Entity{}, errors.New("private key does not match the entity's certificate")
+	}
+
+	return e.successor(privateKey, parent, parentKey)
+}
+
+// Whether the certificates of this entity and another are for the same public key.
+func (e *Entity) HasSameKey(other Entity) bool {
+	key, ok := e.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(other.PublicKey)
+}
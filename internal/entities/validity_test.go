@@ -0,0 +1,75 @@
+package entities
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCertificateValidity(t *testing.T) {
+	privateKey, _ := GenerateKey(ECDSA)
+	entity := Entity{CommonName: "Test entity"}
+	entity.MakeCertificate(privateKey)
+
+	parsed := ParseCertificate(entity.Certificate)
+	if time.Since(parsed.Issued) > time.Minute || parsed.Expires.Sub(parsed.Issued) != CertificateLifetime {
+		t.Errorf("Unexpected validity period: %v to %v", parsed.Issued, parsed.Expires)
+	}
+	if !parsed.ValidAt(time.Now()) || parsed.ValidAt(parsed.Issued.Add(-time.Hour)) || parsed.ValidAt(parsed.Expires.Add(time.Hour)) {
+		t.Error("Certificate should only be valid during its validity period")
+	}
+	if err := parsed.CheckValidAt(parsed.Expires.Add(time.Hour)); !errors.Is(err, ErrCertificateNotValid) {
+		t.Errorf("Expected certificate not valid error: %v", err)
+	}
+	if parsed.IsExpired() || parsed.NeedsRenewal() {
+		t.Error("New certificate should not need renewal")
+	}
+
+	expiring := Entity{CommonName: "Expiring entity", Issued: time.Now().Add(-CertificateLifetime + 24*time.Hour)}
+	expiring.MakeCertificate(privateKey)
+	if !expiring.ExpiresWithin(RenewalPeriod) || expiring.IsExpired() || !expiring.NeedsRenewal() {
+		t.Error("Certificate expiring tomorrow should need renewal")
+	}
+
+	expired := Entity{CommonName: "Expired entity", Issued: time.Now().Add(-CertificateLifetime - time.Hour)}
+	expired.MakeCertificate(privateKey)
+	if !expired.IsExpired() || expired.ExpiresWithin(RenewalPeriod) || !expired.NeedsRenewal() {
+		t.Error("Expired certificate should need renewal")
+	}
+}
+
+func TestRenew(t *testing.T) {
+	privateKey, _ := GenerateKey(Ed25519)
+	entity := Entity{CommonName: "Test entity", Issued: time.Now().Add(-CertificateLifetime)}
+	entity.MakeCertificate(privateKey)
+
+	renewed, err := entity.Renew(privateKey, nil, nil)
+	if err != nil {
+		t.Fatalf("Error renewing entity: %v", err)
+	}
+	if renewed.Uri().Equals(entity.Uri()) || renewed.CommonName != entity.CommonName || !renewed.HasSameKey(entity) {
+		t.Errorf("Renewed entity should be a new certificate for the same name and key: %s", renewed.Uri())
+	}
+	if renewed.IsExpired() || !renewed.IsSelfSigned() {
+		t.Error("Renewed entity should have a new self-signed certificate")
+	}
+
+	otherKey, _ := GenerateKey(Ed25519)
+	if _, err := entity.Renew(otherKey, nil, nil); err == nil {
+		t.Error("Entity should not be renewed with a different key")
+	}
+
+	orgKey, _ := GenerateKey(RSA)
+	organisation := Entity{CommonName: "Organisation"}
+	organisation.MakeCertificate(orgKey)
+	member := Entity{CommonName: "Member"}
+	member.MakeMemberCertificate(privateKey, organisation, orgKey)
+
+	if _, err := member.Renew(privateKey, nil, nil); !errors.Is(err, ErrNotIssuedBy) {
+		t.Errorf("Member should only be renewed by its organisation: %v", err)
+	}
+	renewed, err = member.Renew(privateKey, &organisation, orgKey)
+	if err != nil || renewed.VerifyIssuedBy(organisation) != nil {
+		t.Errorf("Renewed member should be issued by its organisation: %v", err)
+	}
+}
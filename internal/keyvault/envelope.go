@@ -0,0 +1,152 @@
+package keyvault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// The prefix of keys wrapped by an EnvelopeVault, which is followed by the parts of the envelope.
+const ENVELOPE_PREFIX = "envelope-v1."
+
+// EnvelopeVault wraps keys using envelope encryption.
+//
+// Each private key is encrypted with its own random data key using AES-256-GCM, with the entity's hash
+// as additional data. The data key is then itself encrypted with the master key, and stored alongside
+// the encrypted private key and the ID of the master key, so that the master key can be changed and
+// the stored keys wrapped again without decrypting them all at once.
+type EnvelopeVault struct {
+	current string            // The ID of the master key used to wrap new keys
+	masters map[string][]byte // The master keys that can unwrap keys, by ID
+}
+
+// Makes a vault from a base64 encoded 256-bit master key, along with any previous master keys that
+// keys may still be wrapped with.
+func NewEnvelopeVault(masterKey string, previousKeys ...string) (*EnvelopeVault, error) {
+	vault := &EnvelopeVault{masters: make(map[string][]byte)}
+	for n, encoded := range append([]string{masterKey}, previousKeys...) {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("master key %d must be 32 bytes encoded as base64", n)
+		}
+		id := masterKeyId(key)
+		vault.masters[id] = key
+		if n == 0 {
+			vault.current = id
+		}
+	}
+	return vault, nil
+}
+
+// Makes a new random master key, encoded as base64.
+func NewMasterKey() string {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func (v *EnvelopeVault) Name() string {
+	return "Envelope"
+}
+
+// Wraps a key as the envelope prefix followed by the master key ID, the encrypted data key and the
+// encrypted private key, separated by dots.
+func (v *EnvelopeVault) Wrap(entity refs.HashUri, key string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrappedKey, err := seal(v.masters[v.current], dataKey, []byte(v.current))
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataKey, []byte(key), []byte(entity.Hash()))
+	if err != nil {
+		return "", err
+	}
+	return ENVELOPE_PREFIX + v.current + "." + encode(wrappedKey) + "." + encode(ciphertext), nil
+}
+
+// Unwraps a key, or returns it unchanged if it was stored before keys were wrapped.
+func (v *EnvelopeVault) Unwrap(entity refs.HashUri, stored string) (string, error) {
+	if !IsWrapped(stored) {
+		log.Infof("Key for %s is not wrapped, and should be migrated", entity.Short())
+		return stored, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(stored, ENVELOPE_PREFIX), ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: malformed envelope", ErrUnwrap)
+	}
+	masterKey, found := v.masters[parts[0]]
+	if !found {
+		return "", fmt.Errorf("%w: unknown master key %s", ErrUnwrap, parts[0])
+	}
+	dataKey, err := open(masterKey, parts[1], []byte(parts[0]))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnwrap, err)
+	}
+	key, err := open(dataKey, parts[2], []byte(entity.Hash()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnwrap, err)
+	}
+	return string(key), nil
+}
+
+// Whether a stored key is not wrapped, or was wrapped with a master key other than the current one.
+func (v *EnvelopeVault) NeedsRewrap(stored string) bool {
+	return !strings.HasPrefix(stored, ENVELOPE_PREFIX+v.current+".")
+}
+
+// Identifies a master key by the start of its hash, without revealing the key.
+func masterKeyId(key []byte) string {
+	hash := sha256.Sum256(key)
+	return hex.EncodeToString(hash[:4])
+}
+
+// Encrypts the plaintext with AES-256-GCM, returning the random nonce followed by the ciphertext.
+func seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Decrypts a base64 encoded nonce and ciphertext made by seal.
+func open(key []byte, encoded string, additionalData []byte) ([]byte, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encode(bytes []byte) string {
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
@@ -0,0 +1,84 @@
+package keyvault
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+var alice = refs.MakeUri("a1b2c3", "entity")
+var bob = refs.MakeUri("d4e5f6", "entity")
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	vault, err := NewEnvelopeVault(NewMasterKey())
+	if err != nil {
+		t.Fatalf("Error making vault: %v", err)
+	}
+
+	wrapped, err := vault.Wrap(alice, "c2VjcmV0IGtleQ==")
+	if err != nil {
+		t.Fatalf("Error wrapping key: %v", err)
+	}
+	if !IsWrapped(wrapped) || strings.Contains(wrapped, "c2VjcmV0IGtleQ") || EncodingOf(wrapped) != "envelope" {
+		t.Errorf("Key not wrapped: %s", wrapped)
+	}
+	if vault.NeedsRewrap(wrapped) {
+		t.Error("Key wrapped with the current master key should not need wrapping again")
+	}
+
+	key, err := vault.Unwrap(alice, wrapped)
+	if err != nil || key != "c2VjcmV0IGtleQ==" {
+		t.Errorf("Unexpected unwrapped key: %s %v", key, err)
+	}
+
+	if _, err := vault.Unwrap(bob, wrapped); !errors.Is(err, ErrUnwrap) {
+		t.Errorf("Key should not unwrap for another entity: %v", err)
+	}
+	if _, err := vault.Unwrap(alice, wrapped[:len(wrapped)-4]); !errors.Is(err, ErrUnwrap) {
+		t.Errorf("Corrupt key should not unwrap: %v", err)
+	}
+
+	other, _ := NewEnvelopeVault(NewMasterKey())
+	if _, err := other.Unwrap(alice, wrapped); !errors.Is(err, ErrUnwrap) {
+		t.Errorf("Key should not unwrap with a different master key: %v", err)
+	}
+}
+
+func TestEnvelopeMasterKeyChange(t *testing.T) {
+	oldMaster, newMaster := NewMasterKey(), NewMasterKey()
+	oldVault, _ := NewEnvelopeVault(oldMaster)
+	wrapped, _ := oldVault.Wrap(alice, "c2VjcmV0IGtleQ==")
+
+	vault, err := FromConfig(newMaster, " "+oldMaster+" ,")
+	if err != nil {
+		t.Fatalf("Error making vault: %v", err)
+	}
+	if !vault.NeedsRewrap(wrapped) || !vault.NeedsRewrap("c2VjcmV0IGtleQ==") {
+		t.Error("Keys wrapped with a previous master key or not wrapped should need wrapping again")
+	}
+	if key, err := vault.Unwrap(alice, wrapped); err != nil || key != "c2VjcmV0IGtleQ==" {
+		t.Errorf("Key wrapped with a previous master key should unwrap: %s %v", key, err)
+	}
+	if key, err := vault.Unwrap(alice, "c2VjcmV0IGtleQ=="); err != nil || key != "c2VjcmV0IGtleQ==" {
+		t.Errorf("Key stored before wrapping should be returned as it is: %s %v", key, err)
+	}
+}
+
+func TestVaultConfig(t *testing.T) {
+	vault, err := FromConfig("", "")
+	if err != nil || vault.Name() != "Plaintext" {
+		t.Errorf("Expected plaintext vault without a master key: %v %v", vault, err)
+	}
+	if _, err := vault.Unwrap(alice, ENVELOPE_PREFIX+"abc.def.ghi"); !errors.Is(err, ErrUnwrap) {
+		t.Errorf("Plaintext vault should not return wrapped keys: %v", err)
+	}
+
+	if _, err := FromConfig("dG9vIHNob3J0", ""); err == nil {
+		t.Error("Expected error for short master key")
+	}
+	if _, err := FromConfig(NewMasterKey(), "not base64!"); err == nil {
+		t.Error("Expected error for invalid previous master key")
+	}
+}
@@ -0,0 +1,80 @@
+package keyvault
+
+import (
+	"errors"
+	"strings"
+
+	"silvatek.uk/trustedassertions/internal/logging"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+var log = logging.GetLogger("keyvault")
+
+// Returned when a wrapped key cannot be unwrapped, because it is corrupt, was wrapped for a different
+// entity or was wrapped with a master key that the vault does not have.
+var ErrUnwrap = errors.New("unable to unwrap private key")
+
+// KeyVault protects entity private keys while they are stored.
+//
+// Keys are wrapped before they are passed to the data store, and only unwrapped in memory when they
+// are needed for signing. Each wrapped key is bound to its entity, so that it cannot be unwrapped as
+// the key of another entity.
+type KeyVault interface {
+	Name() string
+	Wrap(entity refs.HashUri, key string) (string, error)      // Wraps a base64 encoded private key for storage
+	Unwrap(entity refs.HashUri, stored string) (string, error) // Returns the base64 encoded private key from its stored form
+	NeedsRewrap(stored string) bool                            // Whether a stored key should be wrapped again with the current master key
+}
+
+// The vault used for the keys in the active data store.
+var ActiveKeyVault KeyVault = PlaintextVault{}
+
+// PlaintextVault stores keys as they are, for development and testing without a master key.
+type PlaintextVault struct{}
+
+func (v PlaintextVault) Name() string {
+	return "Plaintext"
+}
+
+func (v PlaintextVault) Wrap(entity refs.HashUri, key string) (string, error) {
+	return key, nil
+}
+
+func (v PlaintextVault) Unwrap(entity refs.HashUri, stored string) (string, error) {
+	if IsWrapped(stored) {
+		return "", ErrUnwrap
+	}
+	return stored, nil
+}
+
+func (v PlaintextVault) NeedsRewrap(stored string) bool {
+	return false
+}
+
+// Whether a stored key has been wrapped by a vault, rather than stored as plain base64 text.
+func IsWrapped(stored string) bool {
+	return strings.HasPrefix(stored, ENVELOPE_PREFIX)
+}
+
+// Returns the encoding of a stored key, for recording alongside it.
+func EncodingOf(stored string) string {
+	if IsWrapped(stored) {
+		return "envelope"
+	}
+	return "base64"
+}
+
+// Makes the vault for a configuration, which is an envelope vault if there is a master key and a
+// plaintext vault otherwise. Previous master keys are separated by commas.
+func FromConfig(masterKey string, previousKeys string) (KeyVault, error) {
+	if masterKey == "" {
+		return PlaintextVault{}, nil
+	}
+	previous := make([]string, 0)
+	for _, key := range strings.Split(previousKeys, ",") {
+		if strings.TrimSpace(key) != "" {
+			previous = append(previous, key)
+		}
+	}
+	return NewEnvelopeVault(masterKey, previous...)
+}
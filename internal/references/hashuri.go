@@ -157,6 +157,16 @@ func (u HashUri) WithType(kind string) HashUri {
 	return u2
 }
 
+// Marshals the URI as its string form, so that it can be included in JSON responses.
+func (u HashUri) MarshalText() ([]byte, error) {
+	return []byte(u.uri), nil
+}
+
+func (u *HashUri) UnmarshalText(text []byte) error {
+	*u = UriFromString(string(text))
+	return nil
+}
+
 func (u HashUri) Equals(other HashUri) bool {
 	return u.Escaped() == other.Escaped()
 }
@@ -168,6 +168,22 @@ func TestEquals(t *testing.T) {
 	}
 }
 
+func TestMarshalText(t *testing.T) {
+	text, err := MakeUri("1234", "statement").MarshalText()
+	if err != nil {
+		t.Errorf("Error marshalling URI: %v", err)
+	}
+	if string(text) != "hash://sha256/1234?type=statement" {
+		t.Errorf("Unexpected marshalled URI: %s", text)
+	}
+
+	var uri HashUri
+	uri.UnmarshalText(text)
+	if uri.String() != "hash://sha256/1234?type=statement" {
+		t.Errorf("Unexpected unmarshalled URI: %s", uri)
+	}
+}
+
 func TestReferenceError(t *testing.T) {
 	err := REF_ERROR
 
@@ -3,9 +3,10 @@ package references
 import "errors"
 
 type Reference struct {
-	Source  HashUri // The source has a reference to the target
-	Target  HashUri
-	Summary string
+	Source   HashUri // The source has a reference to the target
+	Target   HashUri
+	Summary  string
+	Category string // The category of the source, if it is an assertion, so that references can be found by category
 }
 
 // Referenceable is a core data type that can be referenced by an assertion.
@@ -8,6 +8,7 @@ import (
 	"silvatek.uk/trustedassertions/internal/assertions"
 	"silvatek.uk/trustedassertions/internal/auth"
 	"silvatek.uk/trustedassertions/internal/datastore"
+	"silvatek.uk/trustedassertions/internal/entities"
 	"silvatek.uk/trustedassertions/internal/logging"
 	ref "silvatek.uk/trustedassertions/internal/references"
 	"silvatek.uk/trustedassertions/internal/statements"
@@ -22,7 +23,9 @@ func SetupTestData(ctx context.Context, testDataDir string, defaultEntityUri str
 
 	if defaultEntityUri != "" {
 		uri := ref.UriFromString(defaultEntityUri)
-		datastore.ActiveDataStore.StoreKey(uri, defaultEntityKey)
+		if err := datastore.StorePrivateKey(uri, entities.PrivateKeyFromString(defaultEntityKey)); err != nil {
+			log.ErrorfX(ctx, "Error storing default entity key: %v", err)
+		}
 	}
 
 	loadTestData(ctx, testDataDir+"/statements", "Statement", "txt", false)
@@ -59,7 +62,7 @@ func loadTestData(ctx context.Context, dirName string, dataType string, extensio
 
 		datastore.ActiveDataStore.Store(ctx, item)
 
-		if strings.ToLower(dataType) == "assertion" {
+		if assertions.IsAssertionKind(strings.ToLower(dataType)) {
 			addAssertionReferences(ctx, string(content))
 		}
 	}
@@ -0,0 +1,134 @@
+package trust
+
+import (
+	"context"
+	"math"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// BetaModel treats the trusted assertions about a statement as evidence in a Beta distribution.
+//
+// Each assertion contributes its issuer's trust weight as a number of observations, split between
+// true and false according to its category and confidence. Starting from a uniform prior, the score
+// is the mean of the resulting distribution, with a credible interval that narrows as the amount of
+// evidence grows.
+type BetaModel struct {
+	resolver assertions.Resolver
+}
+
+// The number of observations of each outcome assumed before any evidence is seen.
+var BetaPrior = 1.0
+
+// The probability mass within the credible interval reported by the beta model.
+var CredibleMass = 0.95
+
+func NewBetaModel(resolver assertions.Resolver) TrustModel {
+	return &BetaModel{resolver: resolver}
+}
+
+func (m *BetaModel) Name() string {
+	return "beta"
+}
+
+func (m *BetaModel) Score(ctx context.Context, statement refs.HashUri, roots Roots) (Score, error) {
+	return m.ScoreIn(ctx, statement, NewNetwork(ctx, m.resolver, roots))
+}
+
+func (m *BetaModel) ScoreIn(ctx context.Context, statement refs.HashUri, network *Network) (Score, error) {
+	score, err := gatherEvidence(ctx, m.resolver, statement, network)
+	score.Model = m.Name()
+	if err != nil {
+		return score, err
+	}
+
+	alpha, beta := BetaPrior, BetaPrior
+	for _, evidence := range score.Evidence {
+		likelihood, _ := likelihoodOf(evidence.Category, evidence.Confidence)
+		alpha += evidence.Weight * likelihood
+		beta += evidence.Weight * (1 - likelihood)
+	}
+
+	tail := (1 - CredibleMass) / 2
+	score.Value = alpha / (alpha + beta)
+	score.Lower = betaQuantile(tail, alpha, beta)
+	score.Upper = betaQuantile(1-tail, alpha, beta)
+
+	return score, nil
+}
+
+// Returns the value below which the specified proportion of a Beta(a, b) distribution lies.
+func betaQuantile(p float64, a float64, b float64) float64 {
+	low, high := 0.0, 1.0
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if betaCdf(mid, a, b) < p {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// Returns the cumulative distribution function of Beta(a, b) at x, which is the
+// regularised incomplete beta function.
+func betaCdf(x float64, a float64, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lgab, _ := math.Lgamma(a + b)
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly only on one side of the mean, so use symmetry for the other
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(x, a, b) / a
+	}
+	return 1 - front*betaFraction(1-x, b, a)/b
+}
+
+// Evaluates the continued fraction for the incomplete beta function, using Lentz's method.
+func betaFraction(x float64, a float64, b float64) float64 {
+	const maxIterations = 200
+	const epsilon = 1e-12
+	const tiny = 1e-300
+
+	nonZero := func(v float64) float64 {
+		if math.Abs(v) < tiny {
+			return tiny
+		}
+		return v
+	}
+
+	c := 1.0
+	d := 1 / nonZero(1-(a+b)*x/(a+1))
+	h := d
+
+	for m := 1.0; m <= maxIterations; m++ {
+		// Even step
+		aa := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 / nonZero(1+aa*d)
+		c = nonZero(1 + aa/c)
+		h *= d * c
+
+		// Odd step
+		aa = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 / nonZero(1+aa*d)
+		c = nonZero(1 + aa/c)
+		delta := d * c
+		h *= delta
+
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+
+	return h
+}
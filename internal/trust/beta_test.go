@@ -0,0 +1,74 @@
+package trust
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+)
+
+func TestBetaCdf(t *testing.T) {
+	// Beta(1, 1) is uniform, and Beta(a, 1) has cdf x^a
+	assertNearly(t, "uniform cdf", betaCdf(0.3, 1, 1), 0.3)
+	assertNearly(t, "beta(2,1) cdf", betaCdf(0.5, 2, 1), 0.25)
+	assertNearly(t, "beta(3,1) cdf", betaCdf(0.9, 3, 1), math.Pow(0.9, 3))
+	assertNearly(t, "symmetric cdf", betaCdf(0.5, 4.5, 4.5), 0.5)
+
+	assertNearly(t, "uniform quantile", betaQuantile(0.025, 1, 1), 0.025)
+	assertNearly(t, "beta(2,1) quantile", betaQuantile(0.25, 2, 1), 0.5)
+}
+
+func TestBetaScore(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	bob := datastore.CreateEntityWithKey(ctx, "Bob")
+	statement := datastore.CreateStatement(ctx, "The sky is blue")
+
+	model, err := NewModel("beta", datastore.ActiveDataStore)
+	if err != nil {
+		t.Fatalf("Error making beta model: %v", err)
+	}
+
+	score, _ := model.Score(ctx, statement, NewRoots(alice, bob))
+	assertNearly(t, "prior mean", score.Value, 0.5)
+	assertNearly(t, "prior lower bound", score.Lower, 0.025)
+	assertNearly(t, "prior upper bound", score.Upper, 0.975)
+
+	createAssertion(t, ctx, statement, alice, assertions.IsTrue, 1.0)
+	score, _ = model.Score(ctx, statement, NewRoots(alice, bob))
+	if score.Model != "beta" {
+		t.Errorf("Unexpected model name: %s", score.Model)
+	}
+	assertNearly(t, "mean", score.Value, 2.0/3.0)
+	narrow := score.Upper - score.Lower
+
+	createAssertion(t, ctx, statement, bob, assertions.IsTrue, 1.0)
+	score, _ = model.Score(ctx, statement, NewRoots(alice, bob))
+	assertNearly(t, "mean", score.Value, 3.0/4.0)
+	if score.Upper-score.Lower >= narrow {
+		t.Errorf("Interval did not narrow with more evidence: %s", score.Interval())
+	}
+	if !score.HasInterval() || score.Value < score.Lower || score.Value > score.Upper {
+		t.Errorf("Mean %f not within interval %s", score.Value, score.Interval())
+	}
+}
+
+func TestModelRegistry(t *testing.T) {
+	names := ModelNames()
+	if len(names) != 2 || names[0] != "beta" || names[1] != "weighted" {
+		t.Errorf("Unexpected model names: %v", names)
+	}
+
+	model, err := NewModel(DefaultModel, datastore.ActiveDataStore)
+	if err != nil || model.Name() != "weighted" {
+		t.Errorf("Unexpected default model: %v", err)
+	}
+
+	if _, err := NewModel("astrology", datastore.ActiveDataStore); err == nil {
+		t.Error("Expected error for unknown model")
+	}
+}
@@ -0,0 +1,61 @@
+package trust
+
+import (
+	"context"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// The maximum number of statements that can be merged into a single cluster.
+var MaxClusterSize = 20
+
+// Finds the cluster of statements that entities in the network have asserted are the same as
+// the specified statement, directly or through other statements in the cluster.
+//
+// IsSameAs assertions are treated as symmetric, so it does not matter which statement is the
+// subject and which the object. The specified statement is always the first in the cluster.
+func Cluster(ctx context.Context, resolver assertions.Resolver, statement refs.HashUri, network *Network) []refs.HashUri {
+	cluster := []refs.HashUri{statement}
+	visited := map[string]bool{statement.Escaped(): true}
+
+	for n := 0; n < len(cluster) && len(cluster) < MaxClusterSize; n++ {
+		member := cluster[n]
+		found, err := referringAssertions(ctx, resolver, member, func(a assertions.Assertion) bool {
+			return assertions.AssertionTypeOf(a.Category) == assertions.IsSameAs
+		})
+		if err != nil {
+			log.ErrorfX(ctx, "Error fetching equivalents of %s: %v", member, err)
+			continue
+		}
+
+		for _, assertion := range newestAssertions(trustworthy(found, network)) {
+			if network.WeightOf(issuerOf(assertion))*decayOf(assertion) <= 0 {
+				continue
+			}
+			other := otherSide(assertion, member)
+			if other.IsEmpty() || visited[other.Escaped()] || len(cluster) >= MaxClusterSize {
+				continue
+			}
+			visited[other.Escaped()] = true
+			cluster = append(cluster, other)
+		}
+	}
+
+	return cluster
+}
+
+// Returns the statement at the other end of an IsSameAs assertion, or an empty URI if the
+// assertion does not involve the specified statement.
+func otherSide(assertion assertions.Assertion, statement refs.HashUri) refs.HashUri {
+	subject := refs.UriFromString(assertion.Subject)
+	object := refs.UriFromString(assertion.Object)
+	switch {
+	case subject.Equals(statement) && object.Kind() == statement.Kind():
+		return object
+	case object.Equals(statement) && subject.Kind() == statement.Kind():
+		return subject
+	default:
+		return refs.HashUri{}
+	}
+}
@@ -0,0 +1,73 @@
+package trust
+
+import (
+	"context"
+	"testing"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+)
+
+func TestCluster(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	mallory := datastore.CreateEntityWithKey(ctx, "Mallory")
+
+	a := datastore.CreateStatement(ctx, "Water boils at 100C")
+	b := datastore.CreateStatement(ctx, "Water boils at 100 degrees Celsius")
+	c := datastore.CreateStatement(ctx, "The boiling point of water is 100C")
+	d := datastore.CreateStatement(ctx, "Water boils at 50C")
+
+	createAssertion(t, ctx, b, alice, assertions.IsSameAs, 1.0, withObject(a))
+	createAssertion(t, ctx, b, alice, assertions.IsSameAs, 1.0, withObject(c))
+	createAssertion(t, ctx, d, mallory, assertions.IsSameAs, 1.0, withObject(a))
+
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(alice))
+
+	cluster := Cluster(ctx, datastore.ActiveDataStore, a, network)
+	if len(cluster) != 3 {
+		t.Fatalf("Unexpected cluster size: %d", len(cluster))
+	}
+	if !cluster[0].Equals(a) || !containsUri(cluster, b) || !containsUri(cluster, c) {
+		t.Errorf("Unexpected cluster: %v", cluster)
+	}
+	if containsUri(cluster, d) {
+		t.Error("Equivalence asserted by an untrusted entity should be ignored")
+	}
+}
+
+func TestClusterScore(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	bob := datastore.CreateEntityWithKey(ctx, "Bob")
+
+	a := datastore.CreateStatement(ctx, "Water boils at 100C")
+	b := datastore.CreateStatement(ctx, "Water boils at 100 degrees Celsius")
+
+	createAssertion(t, ctx, a, alice, assertions.IsTrue, 1.0)
+	createAssertion(t, ctx, b, bob, assertions.IsTrue, 0.6)
+	createAssertion(t, ctx, b, alice, assertions.IsTrue, 0.2)
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, a, NewRoots(alice, bob))
+	if len(score.Evidence) != 1 {
+		t.Errorf("Unexpected evidence before merging: %d", len(score.Evidence))
+	}
+
+	createAssertion(t, ctx, a, alice, assertions.IsSameAs, 1.0, withObject(b))
+
+	// Alice's newest assertion across the cluster replaces her older one
+	score, _ = NewWeightedModel(datastore.ActiveDataStore).Score(ctx, a, NewRoots(alice, bob))
+	if len(score.Evidence) != 2 {
+		t.Fatalf("Unexpected evidence after merging: %d", len(score.Evidence))
+	}
+	for _, evidence := range score.Evidence {
+		if !evidence.Statement.Equals(b) {
+			t.Errorf("Evidence should come from the variant statement: %v", evidence.Statement)
+		}
+	}
+	assertNearly(t, "score", score.Value, (0.8+0.6)/2)
+}
@@ -0,0 +1,66 @@
+package trust
+
+import (
+	"context"
+	"time"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// Compromise is an assertion that the key of an entity has been compromised, so that
+// assertions it issued from that time on cannot be relied upon.
+type Compromise struct {
+	Assertion refs.HashUri `json:"assertion"`
+	Issuer    refs.HashUri `json:"issuer"` // The entity itself, or an entity trusted from the roots
+	Entity    refs.HashUri `json:"entity"`
+	Since     time.Time    `json:"since"`
+}
+
+// Finds the earliest compromise of an entity's key that was asserted by the entity itself or
+// by another entity in the network.
+func CompromiseOf(ctx context.Context, resolver assertions.Resolver, entity refs.HashUri, network *Network) (Compromise, bool) {
+	var earliest Compromise
+	found := false
+
+	about, err := referringAssertions(ctx, resolver, entity, func(a assertions.Assertion) bool {
+		return assertions.AssertionTypeOf(a.Category) == assertions.IsCompromised &&
+			refs.UriFromString(a.Subject).Hash() == entity.Hash()
+	})
+	if err != nil {
+		log.ErrorfX(ctx, "Error fetching compromises of %s: %v", entity, err)
+		return earliest, false
+	}
+
+	for _, assertion := range about {
+		issuer := issuerOf(assertion)
+		if issuer.Hash() != entity.Hash() && network.WeightOf(issuer) <= 0 {
+			continue
+		}
+		since := assertion.CompromisedSince()
+		if !found || since.Before(earliest.Since) {
+			earliest = Compromise{Assertion: assertion.Uri(), Issuer: issuer, Entity: entity, Since: since}
+			found = true
+		}
+	}
+
+	return earliest, found
+}
+
+// Whether an assertion was issued after the compromise, using its trusted time if it has been timestamped.
+// Assertions with no issue time cannot be shown to predate the compromise, so are always covered.
+func (c Compromise) Covers(assertion assertions.Assertion) bool {
+	issued := assertion.IssueTime()
+	return issued.IsZero() || !issued.Before(c.Since)
+}
+
+// Removes the assertions that were issued by entities in the network after their keys were compromised.
+func trustworthy(found []assertions.Assertion, network *Network) []assertions.Assertion {
+	results := make([]assertions.Assertion, 0, len(found))
+	for _, assertion := range found {
+		if !network.IsCompromised(assertion) {
+			results = append(results, assertion)
+		}
+	}
+	return results
+}
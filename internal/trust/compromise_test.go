@@ -0,0 +1,79 @@
+package trust
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+)
+
+func TestSelfDeclaredCompromise(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := createEntityAt(t, ctx, "Alice", lastMonth)
+	statement := datastore.CreateStatement(ctx, "The sky is blue")
+
+	before := createAssertion(t, ctx, statement, alice, assertions.IsTrue, 0.8, issuedAt(time.Now().Add(-2*time.Hour)))
+	after := createAssertion(t, ctx, statement, alice, assertions.IsFalse, 0.8, issuedAt(time.Now().Add(-30*time.Minute)))
+	createAssertion(t, ctx, alice, alice, assertions.IsCompromised, 1.0, compromisedSince(time.Now().Add(-time.Hour)))
+
+	if _, err := datastore.ActiveDataStore.FetchAssertion(ctx, before.Uri()); err != nil {
+		t.Errorf("Assertion issued before the compromise should be accepted: %v", err)
+	}
+	if _, err := datastore.ActiveDataStore.FetchAssertion(ctx, after.Uri()); !errors.Is(err, assertions.ErrCompromised) {
+		t.Errorf("Assertion issued after the compromise should be rejected: %v", err)
+	}
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(alice))
+	if len(score.Evidence) != 1 || !score.Evidence[0].Assertion.Equals(before.Uri()) {
+		t.Errorf("Only the assertion from before the compromise should be evidence: %v", score.Evidence)
+	}
+	assertNearly(t, "score", score.Value, 0.9)
+}
+
+func TestTrustedCompromise(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	bob := datastore.CreateEntityWithKey(ctx, "Bob")
+	carol := datastore.CreateEntityWithKey(ctx, "Carol")
+	mallory := datastore.CreateEntityWithKey(ctx, "Mallory")
+	statement := datastore.CreateStatement(ctx, "The sky is blue")
+
+	createAssertion(t, ctx, bob, alice, assertions.IsTrusted, 1.0)
+	createAssertion(t, ctx, statement, bob, assertions.IsTrue, 1.0)
+	createAssertion(t, ctx, carol, bob, assertions.IsTrusted, 1.0)
+
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(alice))
+	if network.WeightOf(carol) <= 0 {
+		t.Fatal("Carol should be trusted before Bob's key is compromised")
+	}
+
+	// Declarations by untrusted entities are ignored
+	createAssertion(t, ctx, bob, mallory, assertions.IsCompromised, 1.0, compromisedSince(time.Now().Add(-time.Hour)))
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(alice))
+	if len(score.Evidence) != 1 {
+		t.Errorf("Compromise asserted by an untrusted entity should be ignored: %v", score.Evidence)
+	}
+
+	createAssertion(t, ctx, bob, alice, assertions.IsCompromised, 1.0, compromisedSince(time.Now().Add(-time.Hour)))
+
+	network = NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(alice))
+	node, _ := network.Node(bob)
+	if node.Compromise == nil || !node.Compromise.Issuer.Equals(alice) {
+		t.Errorf("Bob's node should record the compromise asserted by Alice: %v", node.Compromise)
+	}
+	if network.WeightOf(carol) > 0 {
+		t.Error("Trust asserted with a compromised key should not be followed")
+	}
+
+	score, _ = NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(alice))
+	if score.HasEvidence() {
+		t.Errorf("Assertions made with a compromised key should be excluded: %v", score.Evidence)
+	}
+}
@@ -0,0 +1,123 @@
+package trust
+
+import (
+	"context"
+	"sort"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// Contest describes the disagreement between the assertions made about a statement.
+//
+// Unlike a Score, a contest does not depend on any trusted roots: every assertion about the
+// statement is counted, whoever issued it.
+type Contest struct {
+	Statement          refs.HashUri   `json:"statement"`
+	For                []Evidence     `json:"for"`                // Assertions that the statement is true
+	Against            []Evidence     `json:"against"`            // Assertions that the statement is false
+	SelfContradictions []refs.HashUri `json:"selfcontradictions"` // Entities that have asserted both
+	Strength           float64        `json:"strength"`           // How strongly the two sides disagree
+}
+
+// Finds the assertions for and against a statement.
+func FindContest(ctx context.Context, resolver assertions.Resolver, statement refs.HashUri) (Contest, error) {
+	contest := Contest{
+		Statement:          statement,
+		For:                make([]Evidence, 0),
+		Against:            make([]Evidence, 0),
+		SelfContradictions: make([]refs.HashUri, 0),
+	}
+
+	found, err := AssertionsAbout(ctx, resolver, statement)
+	if err != nil {
+		return contest, err
+	}
+
+	for _, assertion := range found {
+		evidence := Evidence{
+			Assertion:  assertion.Uri(),
+			Issuer:     issuerOf(assertion),
+			Category:   assertions.AssertionTypeOf(assertion.Category),
+			Confidence: float64(assertion.Confidence),
+			IssuedAt:   assertion.IssueTime(),
+		}
+		switch evidence.Category.Effect() {
+		case assertions.Supports:
+			contest.For = append(contest.For, evidence)
+		case assertions.Opposes:
+			contest.Against = append(contest.Against, evidence)
+		}
+	}
+
+	for _, issuer := range issuersOf(contest.For) {
+		if containsUri(issuersOf(contest.Against), issuer) {
+			contest.SelfContradictions = append(contest.SelfContradictions, issuer)
+		}
+	}
+
+	contest.Strength = min(sideStrength(contest.For), sideStrength(contest.Against))
+
+	return contest, nil
+}
+
+// Finds the contested statements among those listed, strongest disagreement first.
+func FindContested(ctx context.Context, resolver assertions.Resolver, statements []refs.HashUri) ([]Contest, error) {
+	contests := make([]Contest, 0)
+	for _, statement := range statements {
+		contest, err := FindContest(ctx, resolver, statement)
+		if err != nil {
+			return contests, err
+		}
+		if contest.IsContested() {
+			contests = append(contests, contest)
+		}
+	}
+
+	sort.SliceStable(contests, func(i, j int) bool {
+		if contests[i].Strength != contests[j].Strength {
+			return contests[i].Strength > contests[j].Strength
+		}
+		return contests[i].Size() > contests[j].Size()
+	})
+
+	return contests, nil
+}
+
+// Whether there are assertions on both sides, including an entity contradicting itself.
+func (c Contest) IsContested() bool {
+	return len(c.For) > 0 && len(c.Against) > 0
+}
+
+// Returns the total number of assertions on both sides.
+func (c Contest) Size() int {
+	return len(c.For) + len(c.Against)
+}
+
+// The strength of one side of a contest is the total confidence of its assertions.
+func sideStrength(side []Evidence) float64 {
+	var total float64
+	for _, evidence := range side {
+		total += min(max(evidence.Confidence, 0.0), 1.0)
+	}
+	return total
+}
+
+func issuersOf(side []Evidence) []refs.HashUri {
+	issuers := make([]refs.HashUri, 0)
+	for _, evidence := range side {
+		if !containsUri(issuers, evidence.Issuer) {
+			issuers = append(issuers, evidence.Issuer)
+		}
+	}
+	return issuers
+}
+
+func containsUri(uris []refs.HashUri, uri refs.HashUri) bool {
+	for _, u := range uris {
+		if u.Equals(uri) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package trust
+
+import (
+	"context"
+	"testing"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+func TestFindContest(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	bob := datastore.CreateEntityWithKey(ctx, "Bob")
+	statement := datastore.CreateStatement(ctx, "The sky is blue")
+
+	createAssertion(t, ctx, statement, alice, assertions.IsTrue, 0.8)
+
+	contest, err := FindContest(ctx, datastore.ActiveDataStore, statement)
+	if err != nil {
+		t.Fatalf("Error finding contest: %v", err)
+	}
+	if contest.IsContested() {
+		t.Error("Statement with only supporting assertions should not be contested")
+	}
+
+	createAssertion(t, ctx, statement, bob, assertions.IsFalse, 0.6)
+
+	contest, _ = FindContest(ctx, datastore.ActiveDataStore, statement)
+	if !contest.IsContested() {
+		t.Error("Statement with conflicting assertions should be contested")
+	}
+	if len(contest.SelfContradictions) != 0 {
+		t.Errorf("Unexpected self contradictions: %v", contest.SelfContradictions)
+	}
+	assertNearly(t, "strength", contest.Strength, 0.6)
+}
+
+func TestSelfContradiction(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	statement := datastore.CreateStatement(ctx, "The sky is blue")
+
+	createAssertion(t, ctx, statement, alice, assertions.IsTrue, 0.9)
+	createAssertion(t, ctx, statement, alice, assertions.IsFalse, 0.3)
+
+	contest, _ := FindContest(ctx, datastore.ActiveDataStore, statement)
+	if !contest.IsContested() {
+		t.Error("Self-contradicted statement should be contested")
+	}
+	if len(contest.SelfContradictions) != 1 || !contest.SelfContradictions[0].Equals(alice) {
+		t.Errorf("Unexpected self contradictions: %v", contest.SelfContradictions)
+	}
+}
+
+func TestFindContested(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	bob := datastore.CreateEntityWithKey(ctx, "Bob")
+
+	mild := datastore.CreateStatement(ctx, "Tea is better than coffee")
+	createAssertion(t, ctx, mild, alice, assertions.IsTrue, 0.9)
+	createAssertion(t, ctx, mild, bob, assertions.IsFalse, 0.2)
+
+	strong := datastore.CreateStatement(ctx, "The earth is flat")
+	createAssertion(t, ctx, strong, alice, assertions.IsFalse, 1.0)
+	createAssertion(t, ctx, strong, bob, assertions.IsTrue, 0.7)
+
+	agreed := datastore.CreateStatement(ctx, "Water is wet")
+	createAssertion(t, ctx, agreed, alice, assertions.IsTrue, 1.0)
+	createAssertion(t, ctx, agreed, bob, assertions.IsTrue, 1.0)
+
+	contests, err := FindContested(ctx, datastore.ActiveDataStore, []refs.HashUri{mild, strong, agreed})
+	if err != nil {
+		t.Fatalf("Error finding contested statements: %v", err)
+	}
+	if len(contests) != 2 {
+		t.Fatalf("Unexpected number of contested statements: %d", len(contests))
+	}
+	if !contests[0].Statement.Equals(strong) || !contests[1].Statement.Equals(mild) {
+		t.Errorf("Contested statements not sorted by strength: %v, %v", contests[0].Statement, contests[1].Statement)
+	}
+}
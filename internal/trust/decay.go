@@ -0,0 +1,84 @@
+package trust
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// The half-life of each category of assertion: the age at which an assertion counts for half
+// as much as a new one. Assertions in categories without a half-life do not decay.
+var HalfLives = make(map[assertions.AssertionType]time.Duration)
+
+// Returns the proportion of its weight that an assertion keeps, based on its age and the
+// half-life of its category. Assertions with no issue time are not discounted.
+func decayOf(assertion assertions.Assertion) float64 {
+	halfLife, found := HalfLives[assertions.AssertionTypeOf(assertion.Category)]
+	issued := assertion.IssueTime()
+	if !found || halfLife <= 0 || issued.IsZero() {
+		return 1.0
+	}
+
+	age := time.Since(issued)
+	if age <= 0 {
+		return 1.0
+	}
+	return math.Pow(0.5, float64(age)/float64(halfLife))
+}
+
+// Parses a list of half-lives such as "IsTrue=8760h,IsTrusted=17520h".
+func ParseHalfLives(list string) (map[assertions.AssertionType]time.Duration, error) {
+	halfLives := make(map[assertions.AssertionType]time.Duration)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, found := strings.Cut(item, "=")
+		if !found {
+			return halfLives, fmt.Errorf("half-life must be category=duration: %s", item)
+		}
+		category := assertions.AssertionTypeOf(strings.TrimSpace(name))
+		if category == assertions.Unknown {
+			return halfLives, fmt.Errorf("unknown assertion category: %s", name)
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return halfLives, err
+		}
+		halfLives[category] = duration
+	}
+	return halfLives, nil
+}
+
+// Keeps only the newest assertion made by each issuer about each subject (and object, for
+// assertions that have one), so that an entity which has changed its mind is judged on its
+// latest assertion.
+func newestAssertions(found []assertions.Assertion) []assertions.Assertion {
+	return newestBy(found, func(a assertions.Assertion) string {
+		return issuerOf(a).Escaped() + " " + refs.UriFromString(a.Subject).Hash() + " " + a.Object
+	})
+}
+
+// Keeps only the newest of the assertions that share the same key.
+func newestBy(found []assertions.Assertion, keyOf func(assertions.Assertion) string) []assertions.Assertion {
+	newest := make(map[string]int)
+	results := make([]assertions.Assertion, 0, len(found))
+
+	for _, assertion := range found {
+		key := keyOf(assertion)
+		n, seen := newest[key]
+		if !seen {
+			newest[key] = len(results)
+			results = append(results, assertion)
+		} else if !assertion.IssueTime().Before(results[n].IssueTime()) {
+			results[n] = assertion
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,107 @@
+package trust
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+	"silvatek.uk/trustedassertions/internal/entities"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// Creates an entity whose certificate was issued at a specific time, so that it can make backdated assertions.
+func createEntityAt(t *testing.T, ctx context.Context, name string, issued time.Time) refs.HashUri {
+	privateKey, err := entities.GenerateKey(entities.DefaultKeyAlgorithm)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	entity := entities.Entity{CommonName: name, Issued: issued}
+	entity.MakeCertificate(privateKey)
+	datastore.ActiveDataStore.Store(ctx, &entity)
+	datastore.StorePrivateKey(entity.Uri(), privateKey)
+	return entity.Uri()
+}
+
+// The issue time of entities that make backdated assertions.
+var lastMonth = time.Now().AddDate(0, -1, 0)
+
+func withHalfLives(halfLives map[assertions.AssertionType]time.Duration) func() {
+	previous := HalfLives
+	HalfLives = halfLives
+	return func() { HalfLives = previous }
+}
+
+func TestDecayedScore(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+	defer withHalfLives(map[assertions.AssertionType]time.Duration{assertions.IsTrue: 24 * time.Hour})()
+
+	alice := createEntityAt(t, ctx, "Alice", lastMonth)
+	bob := createEntityAt(t, ctx, "Bob", lastMonth)
+	statement := datastore.CreateStatement(ctx, "The sky is blue")
+
+	createAssertion(t, ctx, statement, alice, assertions.IsTrue, 1.0, issuedAt(time.Now().Add(-24*time.Hour)))
+	createAssertion(t, ctx, statement, bob, assertions.IsFalse, 1.0, issuedAt(time.Now().Add(-240*time.Hour)))
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(alice, bob))
+
+	// Alice's day-old assertion has decayed to half weight, but Bob's falsehood does not decay
+	assertNearly(t, "weight", score.Weight, 1.5)
+	assertNearly(t, "score", score.Value, 1.0/3.0)
+	for _, evidence := range score.Evidence {
+		if evidence.Issuer.Equals(alice) {
+			assertNearly(t, "decay", evidence.Decay, 0.5)
+		}
+	}
+}
+
+func TestNewestAssertionReplacesOlder(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := createEntityAt(t, ctx, "Alice", lastMonth)
+	statement := datastore.CreateStatement(ctx, "The sky is green")
+
+	createAssertion(t, ctx, statement, alice, assertions.IsFalse, 0.4, issuedAt(time.Now().Add(-time.Hour)))
+	newest := createAssertion(t, ctx, statement, alice, assertions.IsTrue, 0.8, issuedAt(time.Now().Add(-time.Minute)))
+	createAssertion(t, ctx, statement, alice, assertions.IsFalse, 1.0, issuedAt(time.Now().Add(-48*time.Hour)))
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(alice))
+
+	if len(score.Evidence) != 1 || !score.Evidence[0].Assertion.Equals(newest.Uri()) {
+		t.Errorf("Expected only the newest assertion as evidence: %v", score.Evidence)
+	}
+	assertNearly(t, "score", score.Value, 0.9)
+}
+
+func TestDecayedTrust(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+	defer withHalfLives(map[assertions.AssertionType]time.Duration{assertions.IsTrusted: time.Hour})()
+
+	alice := createEntityAt(t, ctx, "Alice", lastMonth)
+	bob := createEntityAt(t, ctx, "Bob", lastMonth)
+	createAssertion(t, ctx, bob, alice, assertions.IsTrusted, 1.0, issuedAt(time.Now().Add(-2*time.Hour)))
+
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(alice))
+	assertNearly(t, "weight", network.WeightOf(bob), 0.125)
+}
+
+func TestParseHalfLives(t *testing.T) {
+	halfLives, err := ParseHalfLives("IsTrue=24h, IsTrusted=8760h")
+	if err != nil {
+		t.Errorf("Error parsing half-lives: %v", err)
+	}
+	if halfLives[assertions.IsTrue] != 24*time.Hour || halfLives[assertions.IsTrusted] != 8760*time.Hour {
+		t.Errorf("Unexpected half-lives: %v", halfLives)
+	}
+
+	if _, err := ParseHalfLives("IsPurple=24h"); err == nil {
+		t.Error("Expected error for unknown category")
+	}
+	if _, err := ParseHalfLives("IsTrue"); err == nil {
+		t.Error("Expected error for missing duration")
+	}
+}
@@ -0,0 +1,102 @@
+package trust
+
+import (
+	"context"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// Explanation is a node in the tree of statements, assertions and entities behind a score.
+//
+// The children of a statement are the trusted assertions about it, the child of an assertion is
+// the entity that issued it, and the child of an entity is the assertion (or, for members of an
+// organisation, the certificate) through which it is trusted, which may be the renewal or key rotation
+// linking it to another certificate of the same identity. The leaves of the tree are the trusted
+// root entities.
+type Explanation struct {
+	Uri        refs.HashUri   `json:"uri"`
+	Link       string         `json:"link"`
+	Summary    string         `json:"summary"`
+	Category   string         `json:"category,omitempty"`   // Assertion category, for assertion nodes
+	Confidence float64        `json:"confidence,omitempty"` // Assertion confidence, for assertion nodes
+	Decay      float64        `json:"decay,omitempty"`      // Proportion of weight kept given the age of the assertion, for assertion nodes
+	Weight     float64        `json:"weight"`               // Trust weight of the entity, or of the issuer for assertion nodes
+	Root       bool           `json:"root,omitempty"`       // Whether an entity is one of the trusted roots
+	Children   []*Explanation `json:"children,omitempty"`
+}
+
+// Builds the tree of assertions and entities that contributed to a score.
+func Explain(ctx context.Context, resolver assertions.Resolver, score Score) *Explanation {
+	statement, _ := resolver.FetchStatement(ctx, score.Statement)
+
+	tree := &Explanation{
+		Uri:     score.Statement,
+		Link:    score.Statement.WebPath(),
+		Summary: statement.Summary(),
+		Weight:  score.Value,
+	}
+
+	for _, evidence := range score.Evidence {
+		node := &Explanation{
+			Uri:        evidence.Assertion,
+			Link:       evidence.Assertion.WebPath(),
+			Summary:    evidence.Category.Description(),
+			Category:   evidence.Category.String(),
+			Confidence: evidence.Confidence,
+			Decay:      evidence.Decay,
+			Weight:     evidence.Weight,
+		}
+		node.Children = append(node.Children, explainEntity(ctx, resolver, score.Network, evidence.Issuer, 0))
+		tree.Children = append(tree.Children, node)
+	}
+
+	return tree
+}
+
+// Explains why an entity is trusted, following the chain of IsTrusted assertions, memberships and
+// successions back to a root.
+func explainEntity(ctx context.Context, resolver assertions.Resolver, network *Network, entityUri refs.HashUri, depth int) *Explanation {
+	entity, _ := resolver.FetchEntity(ctx, entityUri)
+
+	node := &Explanation{
+		Uri:     entityUri,
+		Link:    entityUri.WebPath(),
+		Summary: entity.Summary(),
+	}
+
+	if network == nil {
+		return node
+	}
+	trusted, found := network.Node(entityUri)
+	if !found {
+		return node
+	}
+	node.Weight = trusted.Weight
+
+	if trusted.Via == nil || depth > MaxDepth {
+		node.Root = trusted.Via == nil
+		return node
+	}
+
+	link := &Explanation{
+		Uri:        trusted.Via.Assertion,
+		Link:       trusted.Via.Assertion.WebPath(),
+		Summary:    assertions.IsTrusted.Description(),
+		Category:   assertions.IsTrusted.String(),
+		Confidence: trusted.Via.Confidence,
+		Decay:      trusted.Via.Decay,
+		Weight:     network.WeightOf(trusted.Via.Issuer),
+	}
+	if trusted.Via.Membership {
+		link.Summary = "is a member of"
+		link.Category = ""
+	} else if trusted.Via.Succession {
+		link.Summary = "is the same identity as"
+		link.Category = assertions.Replaces.String()
+	}
+	link.Children = append(link.Children, explainEntity(ctx, resolver, network, trusted.Via.Issuer, depth+1))
+	node.Children = append(node.Children, link)
+
+	return node
+}
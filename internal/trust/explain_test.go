@@ -0,0 +1,65 @@
+package trust
+
+import (
+	"context"
+	"testing"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+)
+
+func TestExplainScore(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	root := datastore.CreateEntityWithKey(ctx, "Root")
+	friend := datastore.CreateEntityWithKey(ctx, "Friend")
+	statement := datastore.CreateStatement(ctx, "Grass is green")
+
+	trusts := createAssertion(t, ctx, friend, root, assertions.IsTrusted, 0.8)
+	claim := createAssertion(t, ctx, statement, friend, assertions.IsTrue, 0.9)
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(root))
+	tree := Explain(ctx, datastore.ActiveDataStore, score)
+
+	if tree.Summary != "Grass is green" || tree.Link != statement.WebPath() {
+		t.Errorf("Unexpected statement node: %v", tree)
+	}
+	if len(tree.Children) != 1 {
+		t.Fatalf("Unexpected number of assertions: %d", len(tree.Children))
+	}
+
+	claimNode := tree.Children[0]
+	if !claimNode.Uri.Equals(claim.Uri()) || claimNode.Category != "IsTrue" {
+		t.Errorf("Unexpected assertion node: %v", claimNode)
+	}
+	assertNearly(t, "confidence", claimNode.Confidence, 0.9)
+	assertNearly(t, "weight", claimNode.Weight, 0.4)
+
+	friendNode := claimNode.Children[0]
+	if friendNode.Summary != "Friend" || friendNode.Root {
+		t.Errorf("Unexpected issuer node: %v", friendNode)
+	}
+
+	trustsNode := friendNode.Children[0]
+	if !trustsNode.Uri.Equals(trusts.Uri()) || trustsNode.Category != "IsTrusted" {
+		t.Errorf("Unexpected trust link: %v", trustsNode)
+	}
+
+	rootNode := trustsNode.Children[0]
+	if rootNode.Summary != "Root" || !rootNode.Root || len(rootNode.Children) != 0 {
+		t.Errorf("Unexpected root node: %v", rootNode)
+	}
+}
+
+func TestExplainWithoutNetwork(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	statement := datastore.CreateStatement(ctx, "Nobody has an opinion")
+	tree := Explain(ctx, datastore.ActiveDataStore, Score{Statement: statement})
+
+	if len(tree.Children) != 0 {
+		t.Errorf("Unexpected explanation: %v", tree.Children)
+	}
+}
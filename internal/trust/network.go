@@ -0,0 +1,268 @@
+package trust
+
+import (
+	"context"
+	"sort"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// The proportion of trust that is kept each time it passes from one entity to another.
+var HopDecay = 0.5
+
+// The maximum number of assertions that trust can pass through from a root entity.
+var MaxDepth = 3
+
+// Network is the set of entities that are trusted, either directly as roots or
+// transitively through IsTrusted assertions made by other trusted entities.
+type Network struct {
+	nodes map[string]*Node
+	known map[string]Compromise // Compromises found when the network was last built, by entity
+}
+
+// Node is a single trusted entity within a network.
+type Node struct {
+	Entity refs.HashUri
+	Weight float64
+	Depth  int   // The number of assertions between this entity and a root
+	Via    *Link // The assertion through which the entity is trusted, nil for roots
+
+	Compromise *Compromise // The earliest trusted assertion that the entity's key is compromised, if any
+}
+
+// Link is an assertion by one trusted entity that another entity is trustworthy, a certificate
+// issued by a trusted organisation for one of its members, or a renewal or key rotation linking
+// two certificates of the same identity.
+type Link struct {
+	Assertion  refs.HashUri // The IsTrusted or Replaces assertion, or the member's certificate for memberships
+	Issuer     refs.HashUri
+	Confidence float64
+	Decay      float64 // The proportion of weight kept given the age of the assertion
+	Membership bool    // Whether trust passes through a certificate the issuer issued rather than an assertion
+	Succession bool    // Whether the entities are the same identity, so that trust passes in full
+}
+
+// Builds the network of entities trusted from the roots.
+//
+// Trust spreads from each trusted entity to the entities that it asserts are trustworthy,
+// scaled by the confidence and age of the assertion and by HopDecay, up to MaxDepth assertions
+// away from the roots. Only the newest assertion by an entity about another is used. Where an
+// entity can be reached in several ways, the highest weight is used. Assertions issued after an
+// entity's key was compromised are ignored.
+//
+// Trust also spreads from an organisation to the members it has issued certificates for, as if the
+// organisation had asserted with full confidence that they are trustworthy.
+//
+// Entities whose certificates have been renewed or whose keys have been rotated are treated as one
+// identity over time, so each certificate of the identity is trusted as much as any other, unless
+// the renewal or rotation was made after the original's key was compromised.
+//
+// Compromises can be declared by entities that are only found later in the traversal, after trust
+// has already passed through the compromised key, so the network is built again, skipping anything
+// issued after the compromises found so far, until no more are found.
+func NewNetwork(ctx context.Context, resolver assertions.Resolver, roots Roots) *Network {
+	known := make(map[string]Compromise)
+	for {
+		network := buildNetwork(ctx, resolver, roots, known)
+
+		changed := false
+		for key, node := range network.nodes {
+			network.checkCompromise(ctx, resolver, node)
+			if node.Compromise == nil {
+				continue
+			}
+			if previous, found := known[key]; !found || node.Compromise.Since.Before(previous.Since) {
+				known[key] = *node.Compromise
+				changed = true
+			}
+		}
+		if !changed {
+			return network
+		}
+	}
+}
+
+// Builds the network by a breadth-first traversal from the roots, treating the known compromises as
+// if they had been declared by entities already in the network.
+func buildNetwork(ctx context.Context, resolver assertions.Resolver, roots Roots, known map[string]Compromise) *Network {
+	network := &Network{nodes: make(map[string]*Node), known: known}
+
+	frontier := make([]*Node, 0)
+	for _, root := range roots {
+		frontier = append(frontier, network.add(ctx, resolver, &Node{Entity: root.Entity, Weight: root.Weight})...)
+	}
+
+	for depth := 1; depth <= MaxDepth && len(frontier) > 0; depth++ {
+		next := make([]*Node, 0)
+		for _, node := range frontier {
+			network.checkCompromise(ctx, resolver, node)
+			issued, err := AssertionsIssuedBy(ctx, resolver, node.Entity)
+			if err != nil {
+				log.ErrorfX(ctx, "Error fetching assertions by %s: %v", node.Entity, err)
+				continue
+			}
+			trusting := make([]assertions.Assertion, 0)
+			for _, assertion := range issued {
+				subject := assertions.TypedUri(ctx, resolver, refs.UriFromString(assertion.Subject))
+				if assertions.AssertionTypeOf(assertion.Category).Effect() == assertions.Trusts && subject.Kind() == "entity" && !network.IsCompromised(assertion) {
+					trusting = append(trusting, assertion)
+				}
+			}
+			for _, assertion := range newestAssertions(trusting) {
+				subject := assertions.TypedUri(ctx, resolver, refs.UriFromString(assertion.Subject))
+				decay := decayOf(assertion)
+				candidate := &Node{
+					Entity: subject,
+					Weight: node.Weight * float64(assertion.Confidence) * HopDecay * decay,
+					Depth:  depth,
+					Via: &Link{
+						Assertion:  assertion.Uri(),
+						Issuer:     node.Entity,
+						Confidence: float64(assertion.Confidence),
+						Decay:      decay,
+					},
+				}
+				next = append(next, network.add(ctx, resolver, candidate)...)
+			}
+			for _, member := range assertions.MembersOf(ctx, resolver, node.Entity) {
+				candidate := &Node{
+					Entity: member.Uri(),
+					Weight: node.Weight * HopDecay,
+					Depth:  depth,
+					Via: &Link{
+						Assertion:  member.Uri(),
+						Issuer:     node.Entity,
+						Confidence: 1.0,
+						Decay:      1.0,
+						Membership: true,
+					},
+				}
+				next = append(next, network.add(ctx, resolver, candidate)...)
+			}
+		}
+		frontier = next
+	}
+
+	return network
+}
+
+// Records the earliest compromise of the node's key asserted by the node itself or an entity in the network.
+func (n *Network) checkCompromise(ctx context.Context, resolver assertions.Resolver, node *Node) {
+	if compromise, found := n.compromiseOf(ctx, resolver, node.Entity); found {
+		node.Compromise = &compromise
+	}
+}
+
+// Finds the earliest compromise of an entity's key, either asserted by the entity itself or an entity
+// in the network so far, or found when the network was last built.
+func (n *Network) compromiseOf(ctx context.Context, resolver assertions.Resolver, entity refs.HashUri) (Compromise, bool) {
+	compromise, found := CompromiseOf(ctx, resolver, entity, n)
+	if known, ok := n.known[entity.Escaped()]; ok && (!found || known.Since.Before(compromise.Since)) {
+		return known, true
+	}
+	return compromise, found
+}
+
+// Whether an assertion was issued by an entity in the network after its key was compromised.
+func (n *Network) IsCompromised(assertion assertions.Assertion) bool {
+	node, found := n.nodes[issuerOf(assertion).Escaped()]
+	return found && node.Compromise != nil && node.Compromise.Covers(assertion)
+}
+
+// Adds the node to the network along with the other certificates of the same identity, at the same
+// weight and depth, returning the nodes that were added.
+func (n *Network) add(ctx context.Context, resolver assertions.Resolver, node *Node) []*Node {
+	added := make([]*Node, 0)
+	pending := []*Node{node}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		if !n.update(current) {
+			continue
+		}
+		added = append(added, current)
+
+		entity, err := resolver.FetchEntity(ctx, current.Entity)
+		if err != nil {
+			continue
+		}
+		for _, succession := range assertions.SuccessionsOf(ctx, resolver, entity) {
+			if n.isCompromisedSuccession(ctx, resolver, succession) {
+				continue
+			}
+			pending = append(pending, &Node{
+				Entity: succession.Other(current.Entity),
+				Weight: current.Weight,
+				Depth:  current.Depth,
+				Via: &Link{
+					Assertion:  succession.Assertion,
+					Issuer:     current.Entity,
+					Confidence: 1.0,
+					Decay:      1.0,
+					Succession: true,
+				},
+			})
+		}
+	}
+	return added
+}
+
+// Whether a succession was recorded after the original entity's key was compromised, according to the
+// entity itself or an entity in the network, in which case whoever stole the key could have used it to
+// pass the identity to a key of their own.
+func (n *Network) isCompromisedSuccession(ctx context.Context, resolver assertions.Resolver, succession assertions.Succession) bool {
+	compromise, found := n.compromiseOf(ctx, resolver, succession.Original.Uri())
+	if !found {
+		return false
+	}
+	replacement, err := resolver.FetchAssertion(ctx, succession.Assertion)
+	if err != nil {
+		return true
+	}
+	replacement.ApplyTimestamp(ctx, resolver)
+	return compromise.Covers(replacement)
+}
+
+// Adds the node to the network if it is not already present with a higher weight.
+func (n *Network) update(node *Node) bool {
+	if node.Weight <= 0 {
+		return false
+	}
+	existing, found := n.nodes[node.Entity.Escaped()]
+	if found && existing.Weight >= node.Weight {
+		return false
+	}
+	n.nodes[node.Entity.Escaped()] = node
+	return true
+}
+
+// Returns the weight of trust in the specified entity, or zero if it is not trusted.
+func (n *Network) WeightOf(entityUri refs.HashUri) float64 {
+	node, found := n.nodes[entityUri.Escaped()]
+	if !found {
+		return 0.0
+	}
+	return node.Weight
+}
+
+// Returns the network node for the specified entity, if it is trusted.
+func (n *Network) Node(entityUri refs.HashUri) (*Node, bool) {
+	node, found := n.nodes[entityUri.Escaped()]
+	return node, found
+}
+
+// Returns all the trusted entities, highest weight first.
+func (n *Network) Nodes() []*Node {
+	nodes := make([]*Node, 0, len(n.nodes))
+	for _, node := range n.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Weight == nodes[j].Weight {
+			return nodes[i].Entity.String() < nodes[j].Entity.String()
+		}
+		return nodes[i].Weight > nodes[j].Weight
+	})
+	return nodes
+}
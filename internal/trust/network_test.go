@@ -0,0 +1,237 @@
+package trust
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+	"silvatek.uk/trustedassertions/internal/entities"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+func TestTransitiveTrust(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	root := datastore.CreateEntityWithKey(ctx, "Root")
+	friend := datastore.CreateEntityWithKey(ctx, "Friend")
+	stranger := datastore.CreateEntityWithKey(ctx, "Friend of friend")
+	unknown := datastore.CreateEntityWithKey(ctx, "Unknown")
+
+	createAssertion(t, ctx, friend, root, assertions.IsTrusted, 0.8)
+	createAssertion(t, ctx, stranger, friend, assertions.IsTrusted, 1.0)
+	createAssertion(t, ctx, unknown, unknown, assertions.IsTrusted, 1.0)
+
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(root))
+
+	assertNearly(t, "root weight", network.WeightOf(root), 1.0)
+	assertNearly(t, "friend weight", network.WeightOf(friend), 0.8*HopDecay)
+	assertNearly(t, "stranger weight", network.WeightOf(stranger), 0.8*HopDecay*HopDecay)
+	assertNearly(t, "unknown weight", network.WeightOf(unknown), 0.0)
+
+	node, found := network.Node(stranger)
+	if !found {
+		t.Fatal("Friend of friend not found in network")
+	}
+	if node.Depth != 2 {
+		t.Errorf("Unexpected depth: %d", node.Depth)
+	}
+	if node.Via == nil || !node.Via.Issuer.Equals(friend) {
+		t.Errorf("Unexpected link to friend of friend: %v", node.Via)
+	}
+
+	nodes := network.Nodes()
+	if len(nodes) != 3 || !nodes[0].Entity.Equals(root) {
+		t.Errorf("Unexpected network nodes: %v", nodes)
+	}
+
+	// Subjects without a type are trusted as whatever is stored under their hash
+	createAssertion(t, ctx, refs.MakeUri(unknown.Hash(), ""), root, assertions.IsTrusted, 1.0)
+	network = NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(root))
+	assertNearly(t, "untyped weight", network.WeightOf(unknown), HopDecay)
+}
+
+func TestTrustDepthLimit(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	saved := MaxDepth
+	MaxDepth = 1
+	defer func() { MaxDepth = saved }()
+
+	root := datastore.CreateEntityWithKey(ctx, "Root")
+	friend := datastore.CreateEntityWithKey(ctx, "Friend")
+	stranger := datastore.CreateEntityWithKey(ctx, "Friend of friend")
+
+	createAssertion(t, ctx, friend, root, assertions.IsTrusted, 1.0)
+	createAssertion(t, ctx, stranger, friend, assertions.IsTrusted, 1.0)
+
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(root))
+
+	if network.WeightOf(friend) == 0 {
+		t.Error("Friend should be trusted")
+	}
+	if network.WeightOf(stranger) != 0 {
+		t.Error("Friend of friend should be beyond the depth limit")
+	}
+}
+
+func TestScoreFromTransitiveTrust(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	root := datastore.CreateEntityWithKey(ctx, "Root")
+	friend := datastore.CreateEntityWithKey(ctx, "Friend")
+	statement := datastore.CreateStatement(ctx, "Water is wet")
+
+	createAssertion(t, ctx, friend, root, assertions.IsTrusted, 1.0)
+	createAssertion(t, ctx, statement, friend, assertions.IsTrue, 1.0)
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(root))
+
+	assertNearly(t, "score", score.Value, 1.0)
+	assertNearly(t, "weight", score.Weight, HopDecay)
+}
+
+func TestMembershipTrust(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	organisation := datastore.CreateEntityWithKey(ctx, "Organisation")
+	department, err := datastore.CreateMemberEntity(ctx, "Department", entities.ECDSA, organisation)
+	if err != nil {
+		t.Fatalf("Error creating department: %v", err)
+	}
+	member, err := datastore.CreateMemberEntity(ctx, "Member", entities.Ed25519, department)
+	if err != nil {
+		t.Fatalf("Error creating member: %v", err)
+	}
+	statement := datastore.CreateStatement(ctx, "The department knows best")
+	createAssertion(t, ctx, statement, member, assertions.IsTrue, 1.0)
+
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(organisation))
+	assertNearly(t, "department weight", network.WeightOf(department), HopDecay)
+	assertNearly(t, "member weight", network.WeightOf(member), HopDecay*HopDecay)
+
+	node, _ := network.Node(member)
+	if node == nil || node.Via == nil || !node.Via.Membership || !node.Via.Issuer.Equals(department) {
+		t.Errorf("Member should be trusted through its department: %v", node)
+	}
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(organisation))
+	if len(score.Evidence) != 1 {
+		t.Errorf("Assertion by member of trusted organisation should be evidence: %v", score.Evidence)
+	}
+
+	explanation := Explain(ctx, datastore.ActiveDataStore, score)
+	link := explanation.Children[0].Children[0].Children[0]
+	if link.Summary != "is a member of" || !link.Children[0].Uri.Equals(department) {
+		t.Errorf("Unexpected explanation of membership: %v", link)
+	}
+}
+
+func TestRotatedKeyTrust(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	root := datastore.CreateEntityWithKey(ctx, "Root")
+	friend := datastore.CreateEntityWithKey(ctx, "Friend")
+	createAssertion(t, ctx, friend, root, assertions.IsTrusted, 0.8)
+
+	rotated, err := datastore.RotateEntity(ctx, friend, entities.ECDSA)
+	if err != nil {
+		t.Fatalf("Error rotating key: %v", err)
+	}
+	statement := datastore.CreateStatement(ctx, "Trust survives a new key")
+	createAssertion(t, ctx, statement, rotated, assertions.IsTrue, 1.0)
+
+	// Trust in the old key passes in full to the new key
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(root))
+	assertNearly(t, "rotated weight", network.WeightOf(rotated), network.WeightOf(friend))
+	node, _ := network.Node(rotated)
+	if node == nil || node.Via == nil || !node.Via.Succession || !node.Via.Issuer.Equals(friend) {
+		t.Errorf("Rotated key should be trusted through the original: %v", node)
+	}
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(root))
+	if len(score.Evidence) != 1 {
+		t.Errorf("Assertion with rotated key should be evidence: %v", score.Evidence)
+	}
+	explanation := Explain(ctx, datastore.ActiveDataStore, score)
+	link := explanation.Children[0].Children[0].Children[0]
+	if link.Summary != "is the same identity as" || !link.Children[0].Uri.Equals(friend) {
+		t.Errorf("Unexpected explanation of rotation: %v", link)
+	}
+
+	// Trusting the new key also trusts assertions made with the old one
+	network = NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(rotated))
+	assertNearly(t, "original weight", network.WeightOf(friend), 1.0)
+}
+
+func TestCompromisedRotation(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	root := datastore.CreateEntityWithKey(ctx, "Root")
+	friend := datastore.CreateEntityWithKey(ctx, "Friend")
+	createAssertion(t, ctx, friend, root, assertions.IsTrusted, 0.8)
+
+	// Whoever stole Friend's key rotates it to a key of their own after Root declared it compromised
+	createAssertion(t, ctx, friend, root, assertions.IsCompromised, 1.0, compromisedSince(time.Now().Add(-time.Hour)))
+	rotated, err := datastore.RotateEntity(ctx, friend, entities.ECDSA)
+	if err != nil {
+		t.Fatalf("Error rotating key: %v", err)
+	}
+
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(root))
+	if network.WeightOf(friend) <= 0 {
+		t.Error("Friend should still be trusted for assertions made before the compromise")
+	}
+	assertNearly(t, "rotated weight", network.WeightOf(rotated), 0.0)
+
+	// The compromise is ignored by those who do not trust the entity that declared it
+	network = NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(rotated))
+	assertNearly(t, "original weight", network.WeightOf(friend), 1.0)
+
+	// A compromise declared by the entity itself applies whoever is trusted
+	colleague := datastore.CreateEntityWithKey(ctx, "Colleague")
+	rotated, err = datastore.RotateEntity(ctx, colleague, entities.ECDSA)
+	if err != nil {
+		t.Fatalf("Error rotating key: %v", err)
+	}
+	createAssertion(t, ctx, colleague, colleague, assertions.IsCompromised, 1.0, compromisedSince(time.Now().Add(-time.Hour)))
+	network = NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(rotated))
+	assertNearly(t, "self-compromised weight", network.WeightOf(colleague), 0.0)
+}
+
+func TestCompromiseDeclaredFurtherOut(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	root := datastore.CreateEntityWithKey(ctx, "Root")
+	friend := createEntityAt(t, ctx, "Friend", lastMonth)
+	colleague := datastore.CreateEntityWithKey(ctx, "Colleague")
+	auditor := datastore.CreateEntityWithKey(ctx, "Auditor")
+	mallory := datastore.CreateEntityWithKey(ctx, "Mallory")
+
+	createAssertion(t, ctx, friend, root, assertions.IsTrusted, 1.0)
+	createAssertion(t, ctx, colleague, friend, assertions.IsTrusted, 1.0, issuedAt(time.Now().Add(-2*time.Hour)))
+	createAssertion(t, ctx, auditor, colleague, assertions.IsTrusted, 1.0)
+
+	// The auditor, two hops further out than the friend, declares the friend's key compromised,
+	// and whoever took the key uses it to bring their own entity into the network
+	createAssertion(t, ctx, friend, auditor, assertions.IsCompromised, 1.0, compromisedSince(time.Now().Add(-time.Hour)))
+	createAssertion(t, ctx, mallory, friend, assertions.IsTrusted, 1.0)
+
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(root))
+	node, found := network.Node(friend)
+	if !found || node.Compromise == nil || !node.Compromise.Issuer.Equals(auditor) {
+		t.Errorf("Friend should be in the network with the auditor's compromise: %v", node)
+	}
+	if network.WeightOf(auditor) <= 0 {
+		t.Error("Auditor should be trusted through assertions made before the compromise")
+	}
+	assertNearly(t, "mallory weight", network.WeightOf(mallory), 0.0)
+}
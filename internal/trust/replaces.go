@@ -0,0 +1,69 @@
+package trust
+
+import (
+	"context"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// Replacement is an assertion by a trusted entity that one statement, document or entity has
+// been replaced by a newer one.
+type Replacement struct {
+	Assertion   refs.HashUri `json:"assertion"`
+	Issuer      refs.HashUri `json:"issuer"`
+	Original    refs.HashUri `json:"original"`    // The object of the assertion, which has been replaced
+	Replacement refs.HashUri `json:"replacement"` // The subject of the assertion, which replaces the original
+	Weight      float64      `json:"weight"`      // The trust weight of the issuer, discounted by the age of the assertion
+}
+
+// Finds the replacement for an item that is asserted by the most trusted entity in the network.
+//
+// Replaces assertions by entities outside the network are ignored, so that nobody can hide
+// an item simply by claiming to have replaced it.
+func ReplacementOf(ctx context.Context, resolver assertions.Resolver, uri refs.HashUri, network *Network) (Replacement, bool) {
+	var best Replacement
+
+	found, err := referringAssertions(ctx, resolver, uri, func(a assertions.Assertion) bool {
+		return assertions.AssertionTypeOf(a.Category) == assertions.Replaces && refs.UriFromString(a.Object).Equals(uri)
+	})
+	if err != nil {
+		log.ErrorfX(ctx, "Error fetching replacements for %s: %v", uri, err)
+		return best, false
+	}
+
+	for _, assertion := range newestAssertions(trustworthy(found, network)) {
+		issuer := issuerOf(assertion)
+		weight := network.WeightOf(issuer) * decayOf(assertion)
+		if weight <= best.Weight {
+			continue
+		}
+		best = Replacement{
+			Assertion:   assertion.Uri(),
+			Issuer:      issuer,
+			Original:    uri,
+			Replacement: refs.UriFromString(assertion.Subject),
+			Weight:      weight,
+		}
+	}
+
+	return best, best.Weight > 0
+}
+
+// Follows the chain of trusted replacements from an item to its latest version, returning each
+// replacement in turn. The chain is empty if the item has not been replaced.
+func Supersessions(ctx context.Context, resolver assertions.Resolver, uri refs.HashUri, network *Network) []Replacement {
+	chain := make([]Replacement, 0)
+	visited := map[string]bool{uri.Escaped(): true}
+
+	current := uri
+	for {
+		replacement, found := ReplacementOf(ctx, resolver, current, network)
+		if !found || visited[replacement.Replacement.Escaped()] {
+			return chain
+		}
+		visited[replacement.Replacement.Escaped()] = true
+		chain = append(chain, replacement)
+		current = replacement.Replacement
+	}
+}
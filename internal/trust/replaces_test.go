@@ -0,0 +1,59 @@
+package trust
+
+import (
+	"context"
+	"testing"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+)
+
+func TestSupersessions(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	mallory := datastore.CreateEntityWithKey(ctx, "Mallory")
+
+	v1 := datastore.CreateStatement(ctx, "The meeting is on Monday")
+	v2 := datastore.CreateStatement(ctx, "The meeting is on Tuesday")
+	v3 := datastore.CreateStatement(ctx, "The meeting is on Wednesday")
+	fake := datastore.CreateStatement(ctx, "The meeting is cancelled")
+
+	createAssertion(t, ctx, v2, alice, assertions.Replaces, 1.0, withObject(v1))
+	createAssertion(t, ctx, v3, alice, assertions.Replaces, 1.0, withObject(v2))
+	createAssertion(t, ctx, fake, mallory, assertions.Replaces, 1.0, withObject(v3))
+
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(alice))
+
+	chain := Supersessions(ctx, datastore.ActiveDataStore, v1, network)
+	if len(chain) != 2 {
+		t.Fatalf("Unexpected length of supersession chain: %d", len(chain))
+	}
+	if !chain[0].Replacement.Equals(v2) || !chain[1].Replacement.Equals(v3) {
+		t.Errorf("Unexpected supersession chain: %v", chain)
+	}
+
+	if len(Supersessions(ctx, datastore.ActiveDataStore, v3, network)) != 0 {
+		t.Error("Replacement by an untrusted entity should be ignored")
+	}
+}
+
+func TestSupersessionCycle(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	v1 := datastore.CreateStatement(ctx, "Version one")
+	v2 := datastore.CreateStatement(ctx, "Version two")
+
+	createAssertion(t, ctx, v2, alice, assertions.Replaces, 1.0, withObject(v1))
+	createAssertion(t, ctx, v1, alice, assertions.Replaces, 1.0, withObject(v2))
+
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(alice))
+
+	chain := Supersessions(ctx, datastore.ActiveDataStore, v1, network)
+	if len(chain) != 1 || !chain[0].Replacement.Equals(v2) {
+		t.Errorf("Unexpected supersession chain for cycle: %v", chain)
+	}
+}
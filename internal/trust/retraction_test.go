@@ -0,0 +1,37 @@
+package trust
+
+import (
+	"context"
+	"testing"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+)
+
+func TestRetractedAssertionsIgnored(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	bob := datastore.CreateEntityWithKey(ctx, "Bob")
+	statement := datastore.CreateStatement(ctx, "The moon is made of cheese")
+
+	retracted := createAssertion(t, ctx, statement, alice, assertions.IsTrue, 1.0)
+	kept := createAssertion(t, ctx, statement, bob, assertions.IsFalse, 1.0)
+	createAssertion(t, ctx, retracted.Uri(), alice, assertions.Retracts, 1.0)
+
+	if _, found := assertions.RetractionOf(ctx, datastore.ActiveDataStore, *retracted); !found {
+		t.Error("Retraction by the issuer not found")
+	}
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(alice, bob))
+	if len(score.Evidence) != 1 || !score.Evidence[0].Assertion.Equals(kept.Uri()) {
+		t.Errorf("Retracted assertion should not be evidence: %v", score.Evidence)
+	}
+	assertNearly(t, "score", score.Value, 0.0)
+
+	contest, _ := FindContest(ctx, datastore.ActiveDataStore, statement)
+	if contest.IsContested() {
+		t.Error("Retracted assertion should not contest the statement")
+	}
+}
@@ -0,0 +1,70 @@
+package trust
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+)
+
+func timestamp(t *testing.T, ctx context.Context, assertion *assertions.Assertion) {
+	if _, err := datastore.TimestampAssertion(ctx, assertion); err != nil {
+		t.Fatalf("Error timestamping assertion: %v", err)
+	}
+}
+
+func TestTimestampedDecay(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+	defer withHalfLives(map[assertions.AssertionType]time.Duration{assertions.IsTrue: 24 * time.Hour})()
+	defer assertions.UseTimestampAuthority(datastore.CreateEntityWithKey(ctx, "Timestamp authority"))()
+
+	alice := createEntityAt(t, ctx, "Alice", lastMonth)
+	bob := createEntityAt(t, ctx, "Bob", lastMonth)
+	statement := datastore.CreateStatement(ctx, "The sky is blue")
+
+	// Alice's assertion claims to be ten days old, but was only received now
+	received := createAssertion(t, ctx, statement, alice, assertions.IsTrue, 1.0, issuedAt(time.Now().Add(-240*time.Hour)))
+	timestamp(t, ctx, received)
+	createAssertion(t, ctx, statement, bob, assertions.IsTrue, 1.0, issuedAt(time.Now().Add(-240*time.Hour)))
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(alice, bob))
+	for _, evidence := range score.Evidence {
+		if evidence.Issuer.Equals(alice) {
+			assertNearly(t, "timestamped decay", evidence.Decay, 1.0)
+			if time.Since(evidence.IssuedAt) > time.Minute {
+				t.Errorf("Evidence should be dated by its timestamp: %v", evidence.IssuedAt)
+			}
+		} else if evidence.Decay > 0.01 {
+			t.Errorf("Assertion without a timestamp should decay from its claimed time: %v", evidence.Decay)
+		}
+	}
+}
+
+func TestTimestampedCompromise(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+	defer assertions.UseTimestampAuthority(datastore.CreateEntityWithKey(ctx, "Timestamp authority"))()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	bob := createEntityAt(t, ctx, "Bob", lastMonth)
+	statement := datastore.CreateStatement(ctx, "The sky is blue")
+
+	createAssertion(t, ctx, bob, alice, assertions.IsTrusted, 1.0)
+	createAssertion(t, ctx, bob, alice, assertions.IsCompromised, 1.0, compromisedSince(time.Now().Add(-time.Hour)))
+
+	// Whoever holds Bob's key backdates an assertion to before the compromise, but it is received after it
+	backdated := createAssertion(t, ctx, statement, bob, assertions.IsTrue, 1.0, issuedAt(time.Now().Add(-2*time.Hour)))
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(alice))
+	if !score.HasEvidence() {
+		t.Fatal("Without a timestamp, the backdated assertion should be taken at its word")
+	}
+
+	timestamp(t, ctx, backdated)
+	score, _ = NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(alice))
+	if score.HasEvidence() {
+		t.Errorf("Assertion timestamped after the compromise should be excluded: %v", score.Evidence)
+	}
+}
@@ -0,0 +1,269 @@
+package trust
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/auth"
+	"silvatek.uk/trustedassertions/internal/logging"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// TrustModel estimates how likely a statement is to be true, by following the assertions
+// made about it back to a set of trusted root entities.
+type TrustModel interface {
+	Name() string
+	Score(ctx context.Context, statement refs.HashUri, roots Roots) (Score, error)
+	// Scores a statement with a network that has already been built from the roots, so that one
+	// network can be shared between several scores.
+	ScoreIn(ctx context.Context, statement refs.HashUri, network *Network) (Score, error)
+}
+
+// Root is an entity that the user of a trust model trusts, with the weight of that trust (0.0 to 1.0).
+type Root struct {
+	Entity refs.HashUri
+	Weight float64
+}
+
+// Roots is the set of trusted entities that a trust model starts from.
+type Roots []Root
+
+// Score is the outcome of applying a trust model to a statement.
+type Score struct {
+	Statement refs.HashUri `json:"statement"`
+	Model     string       `json:"model"`
+	Value     float64      `json:"value"`           // Estimated likelihood that the statement is true, from 0.0 to 1.0
+	Lower     float64      `json:"lower,omitempty"` // Lower bound of the credible interval, for models that estimate one
+	Upper     float64      `json:"upper,omitempty"` // Upper bound of the credible interval, for models that estimate one
+	Weight    float64      `json:"weight"`          // Total weight of the evidence that the value is based on
+	Evidence  []Evidence   `json:"evidence"`        // The trusted assertions that contributed to the score
+	Network   *Network     `json:"-"`               // The network of trusted entities used to weight the evidence
+}
+
+// Evidence is a single trusted assertion that contributed to a score.
+type Evidence struct {
+	Assertion  refs.HashUri             `json:"assertion"`
+	Statement  refs.HashUri             `json:"statement"` // The statement, or equivalent statement, that the assertion is about
+	Issuer     refs.HashUri             `json:"issuer"`
+	Category   assertions.AssertionType `json:"category"`
+	Confidence float64                  `json:"confidence"`
+	IssuedAt   time.Time                `json:"issuedat"`
+	Decay      float64                  `json:"decay"`  // The proportion of weight kept given the age of the assertion
+	Weight     float64                  `json:"weight"` // The trust weight of the issuer, discounted by the decay
+}
+
+// The roots used when the user of a trust model has not supplied their own.
+var DefaultRoots Roots
+
+// UserFetcher fetches the users whose own trust roots are used in place of the default roots.
+type UserFetcher interface {
+	FetchUser(ctx context.Context, id string) (auth.User, error)
+}
+
+// Returns the trust roots chosen by the named user, or DefaultRoots for anonymous visitors, who have
+// an empty username, and for users who have not chosen any roots of their own.
+func RootsForUser(ctx context.Context, users UserFetcher, username string) Roots {
+	if username == "" {
+		return DefaultRoots
+	}
+
+	user, err := users.FetchUser(ctx, username)
+	if err != nil || len(user.TrustRoots) == 0 {
+		return DefaultRoots
+	}
+
+	roots := make(Roots, 0, len(user.TrustRoots))
+	for _, root := range user.TrustRoots {
+		roots = append(roots, Root{Entity: refs.UriFromString(root.Entity), Weight: root.Weight})
+	}
+	return roots
+}
+
+// The name of the trust model used when no other model has been chosen.
+var DefaultModel = "weighted"
+
+// The trust models that can be chosen by name.
+var models = map[string]func(assertions.Resolver) TrustModel{
+	"weighted": NewWeightedModel,
+	"beta":     NewBetaModel,
+}
+
+var log = logging.GetLogger("trust")
+
+// Makes the trust model with the specified name, or returns an error if there is no such model.
+func NewModel(name string, resolver assertions.Resolver) (TrustModel, error) {
+	constructor, ok := models[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown trust model: %s", name)
+	}
+	return constructor(resolver), nil
+}
+
+// Returns the names of all the trust models, in alphabetical order.
+func ModelNames() []string {
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Makes a set of roots from a list of entity URIs, each given full weight.
+func NewRoots(entityUris ...refs.HashUri) Roots {
+	roots := make(Roots, 0)
+	for _, uri := range entityUris {
+		if !uri.IsEmpty() {
+			roots = append(roots, Root{Entity: uri, Weight: 1.0})
+		}
+	}
+	return roots
+}
+
+// Returns the weight of trust in the specified entity, or zero if it is not one of the roots.
+func (r Roots) WeightOf(entityUri refs.HashUri) float64 {
+	for _, root := range r {
+		if root.Entity.Equals(entityUri) {
+			return root.Weight
+		}
+	}
+	return 0.0
+}
+
+func (s Score) HasEvidence() bool {
+	return s.Weight > 0
+}
+
+// Returns the score value as a whole-number percentage.
+func (s Score) Percent() string {
+	return percent(s.Value)
+}
+
+// Whether the model estimated a credible interval around the score value.
+func (s Score) HasInterval() bool {
+	return s.Upper > s.Lower
+}
+
+// Returns the credible interval as a range of whole-number percentages.
+func (s Score) Interval() string {
+	return percent(s.Lower) + " to " + percent(s.Upper)
+}
+
+func percent(value float64) string {
+	return fmt.Sprintf("%.0f%%", value*100)
+}
+
+// Collects the assertions about a statement, and the statements in its cluster, that were issued
+// by entities in the network, as the evidence for a score. Only the newest
+// assertion from each issuer is used, and older assertions are discounted according to HalfLives.
+// Assertions issued after the issuer's key was compromised are ignored.
+// The score value is left neutral (0.5) for the trust model to calculate.
+func gatherEvidence(ctx context.Context, resolver assertions.Resolver, statement refs.HashUri, network *Network) (Score, error) {
+	score := Score{Statement: statement, Value: 0.5, Evidence: make([]Evidence, 0), Network: network}
+
+	relevant := make([]assertions.Assertion, 0)
+	for _, member := range Cluster(ctx, resolver, statement, network) {
+		found, err := AssertionsAbout(ctx, resolver, member)
+		if err != nil {
+			return score, err
+		}
+		for _, assertion := range found {
+			category := assertions.AssertionTypeOf(assertion.Category)
+			if _, ok := likelihoodOf(category, float64(assertion.Confidence)); ok && !network.IsCompromised(assertion) {
+				relevant = append(relevant, assertion)
+			}
+		}
+	}
+
+	// The statements in a cluster are treated as one, so each issuer only counts once across the cluster
+	newest := newestBy(relevant, func(a assertions.Assertion) string {
+		return issuerOf(a).Escaped()
+	})
+
+	for _, assertion := range newest {
+		issuer := issuerOf(assertion)
+		decay := decayOf(assertion)
+		weight := network.WeightOf(issuer) * decay
+		if weight <= 0 {
+			continue
+		}
+
+		score.Weight += weight
+		score.Evidence = append(score.Evidence, Evidence{
+			Assertion:  assertion.Uri(),
+			Statement:  refs.UriFromString(assertion.Subject),
+			Issuer:     issuer,
+			Category:   assertions.AssertionTypeOf(assertion.Category),
+			Confidence: float64(assertion.Confidence),
+			IssuedAt:   assertion.IssueTime(),
+			Decay:      decay,
+			Weight:     weight,
+		})
+	}
+
+	return score, nil
+}
+
+// Returns the URI of the entity that issued an assertion.
+func issuerOf(assertion assertions.Assertion) refs.HashUri {
+	issuer := refs.UriFromString(assertion.Issuer)
+	if !issuer.HasType() {
+		issuer = issuer.WithType("entity")
+	}
+	return issuer
+}
+
+// Fetches all the assertions that have the specified URI as their subject.
+//
+// Assertions are found through the reference index, so only assertions that have had
+// their references stored will be returned.
+func AssertionsAbout(ctx context.Context, resolver assertions.Resolver, subject refs.HashUri) ([]assertions.Assertion, error) {
+	return referringAssertions(ctx, resolver, subject, func(a assertions.Assertion) bool {
+		return refs.UriFromString(a.Subject).Equals(subject)
+	})
+}
+
+// Fetches all the assertions that were issued by the specified entity.
+func AssertionsIssuedBy(ctx context.Context, resolver assertions.Resolver, issuer refs.HashUri) ([]assertions.Assertion, error) {
+	return referringAssertions(ctx, resolver, issuer, func(a assertions.Assertion) bool {
+		return issuerOf(a).Hash() == issuer.Hash()
+	})
+}
+
+// Fetches the assertions that refer to the specified URI and match the filter.
+// Assertions that have expired, are not yet valid or have been retracted by their issuer are skipped.
+// Any timestamps from the timestamp authority are applied, so that trusted times are used for decay and compromises.
+func referringAssertions(ctx context.Context, resolver assertions.Resolver, uri refs.HashUri, filter func(assertions.Assertion) bool) ([]assertions.Assertion, error) {
+	results := make([]assertions.Assertion, 0)
+
+	references, err := resolver.FetchRefs(ctx, uri)
+	if err != nil {
+		return results, err
+	}
+
+	seen := make(map[string]bool)
+	for _, ref := range references {
+		if !assertions.IsAssertionKind(ref.Source.Kind()) || seen[ref.Source.Escaped()] {
+			continue
+		}
+		seen[ref.Source.Escaped()] = true
+
+		assertion, err := resolver.FetchAssertion(ctx, ref.Source)
+		if err != nil {
+			log.DebugfX(ctx, "Skipping assertion %s: %v", ref.Source, err)
+			continue
+		}
+		if !assertion.IsActive() || !filter(assertion) {
+			continue
+		}
+		if assertion.Annotate(ctx, resolver).Retraction != nil {
+			continue
+		}
+		results = append(results, assertion)
+	}
+
+	return results, nil
+}
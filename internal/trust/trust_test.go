@@ -0,0 +1,199 @@
+package trust
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+func setupTestStore() {
+	datastore.InitInMemoryDataStore()
+	assertions.PublicKeyResolver = datastore.ActiveDataStore
+}
+
+// Sets a claim of an assertion made by a test before it is signed.
+type assertionOption func(*assertions.Assertion)
+
+// Sets the object of an assertion, such as the older URI that a Replaces assertion supersedes.
+func withObject(object refs.HashUri) assertionOption {
+	return func(a *assertions.Assertion) { a.Object = object.String() }
+}
+
+// Backdates an assertion, which is then stored without checks because the datastore would date it now.
+func issuedAt(issued time.Time) assertionOption {
+	return func(a *assertions.Assertion) {
+		a.IssuedAt = jwt.NewNumericDate(issued)
+		a.NotBefore = a.IssuedAt
+	}
+}
+
+// Limits the time during which an assertion is in force.
+func validBetween(from time.Time, until time.Time) assertionOption {
+	return func(a *assertions.Assertion) {
+		a.NotBefore = jwt.NewNumericDate(from)
+		a.ExpiresAt = jwt.NewNumericDate(until)
+	}
+}
+
+// Sets the time from which an IsCompromised assertion says the key of its subject was compromised.
+func compromisedSince(since time.Time) assertionOption {
+	return func(a *assertions.Assertion) { a.Since = jwt.NewNumericDate(since) }
+}
+
+// Creates an assertion about a subject, signed with the stored private key of the issuing entity.
+func createAssertion(t *testing.T, ctx context.Context, subject refs.HashUri, entityUri refs.HashUri, kind assertions.AssertionType, confidence float64, options ...assertionOption) *assertions.Assertion {
+	privateKey, err := datastore.FetchPrivateKey(entityUri)
+	if err != nil {
+		t.Fatalf("Error fetching key: %v", err)
+	}
+
+	assertion := assertions.NewAssertion(kind)
+	assertion.Subject = subject.String()
+	assertion.Issuer = entityUri.String()
+	assertion.Confidence = float32(confidence)
+	for _, option := range options {
+		option(&assertion)
+	}
+
+	if assertion.IssuedAt != nil {
+		assertion.MakeJwt(privateKey)
+		datastore.ActiveDataStore.Store(ctx, &assertion)
+		datastore.CreateReferences(ctx, &assertion)
+		return &assertion
+	}
+
+	created, err := datastore.CreateSignedAssertion(ctx, assertion, privateKey)
+	if err != nil {
+		t.Fatalf("Error creating assertion: %v", err)
+	}
+	return created
+}
+
+func assertNearly(t *testing.T, name string, actual float64, expected float64) {
+	if math.Abs(actual-expected) > 0.0001 {
+		t.Errorf("Unexpected %s: %f (expected %f)", name, actual, expected)
+	}
+}
+
+func TestWeightedScore(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	bob := datastore.CreateEntityWithKey(ctx, "Bob")
+	statement := datastore.CreateStatement(ctx, "The sky is blue")
+
+	createAssertion(t, ctx, statement, alice, assertions.IsTrue, 0.8)
+	createAssertion(t, ctx, statement, bob, assertions.IsFalse, 0.6)
+
+	model := NewWeightedModel(datastore.ActiveDataStore)
+	if model.Name() != "weighted" {
+		t.Errorf("Unexpected model name: %s", model.Name())
+	}
+
+	score, err := model.Score(ctx, statement, NewRoots(alice))
+	if err != nil {
+		t.Errorf("Error scoring statement: %v", err)
+	}
+	assertNearly(t, "score", score.Value, 0.9)
+	if len(score.Evidence) != 1 {
+		t.Errorf("Unexpected amount of evidence: %d", len(score.Evidence))
+	}
+
+	// A network built once can be shared between scores
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(alice))
+	shared, _ := model.ScoreIn(ctx, statement, network)
+	assertNearly(t, "shared network score", shared.Value, score.Value)
+	if shared.Network != network {
+		t.Error("Score should use the network it was given")
+	}
+
+	score, _ = model.Score(ctx, statement, NewRoots(alice, bob))
+	assertNearly(t, "score", score.Value, 0.55)
+	assertNearly(t, "weight", score.Weight, 2.0)
+
+	score, _ = model.Score(ctx, statement, Roots{{Entity: alice, Weight: 1.0}, {Entity: bob, Weight: 0.5}})
+	assertNearly(t, "score", score.Value, (0.9+0.5*0.2)/1.5)
+}
+
+func TestScoreWithoutTrustedEvidence(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	statement := datastore.CreateStatement(ctx, "The sky is green")
+	createAssertion(t, ctx, statement, alice, assertions.IsFalse, 0.9)
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots())
+
+	if score.HasEvidence() {
+		t.Error("Score with no roots should not have evidence")
+	}
+	if score.Percent() != "50%" {
+		t.Errorf("Unexpected neutral score: %s", score.Percent())
+	}
+}
+
+func TestAssertionsAbout(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	statement1 := datastore.CreateStatement(ctx, "Statement one")
+	statement2 := datastore.CreateStatement(ctx, "Statement two")
+	createAssertion(t, ctx, statement1, alice, assertions.IsTrue, 0.9)
+	createAssertion(t, ctx, statement1, alice, assertions.IsTrue, 0.7)
+	createAssertion(t, ctx, statement2, alice, assertions.IsTrue, 0.5)
+
+	found, err := AssertionsAbout(ctx, datastore.ActiveDataStore, statement1)
+	if err != nil {
+		t.Errorf("Error finding assertions: %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("Unexpected number of assertions about statement: %d", len(found))
+	}
+
+	// The issuer is referenced by all the assertions, but is not their subject
+	found, _ = AssertionsAbout(ctx, datastore.ActiveDataStore, alice)
+	if len(found) != 0 {
+		t.Errorf("Unexpected number of assertions about entity: %d", len(found))
+	}
+}
+
+func TestRootWeights(t *testing.T) {
+	uri := refs.MakeUri("1234", "entity")
+	roots := NewRoots(uri, refs.EMPTY_URI)
+
+	if len(roots) != 1 {
+		t.Errorf("Unexpected number of roots: %d", len(roots))
+	}
+	if roots.WeightOf(refs.MakeUri("1234", "")) != 1.0 {
+		t.Error("Root entity should have full weight")
+	}
+	if roots.WeightOf(refs.MakeUri("5678", "entity")) != 0.0 {
+		t.Error("Unknown entity should have no weight")
+	}
+}
+
+func TestRegisteredCategoryEffect(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	statement := datastore.CreateStatement(ctx, "The sky is blue")
+
+	// IsMisleading has no constant of its own, and is used through its registered trust effect
+	createAssertion(t, ctx, statement, alice, assertions.AssertionTypeOf("IsMisleading"), 0.6)
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(alice))
+	if len(score.Evidence) != 1 {
+		t.Fatalf("Unexpected evidence: %v", score.Evidence)
+	}
+	assertNearly(t, "score", score.Value, 0.2)
+}
@@ -0,0 +1,35 @@
+package trust
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+)
+
+func TestInactiveAssertionsIgnored(t *testing.T) {
+	setupTestStore()
+	ctx := context.Background()
+
+	alice := datastore.CreateEntityWithKey(ctx, "Alice")
+	bob := datastore.CreateEntityWithKey(ctx, "Bob")
+	carol := datastore.CreateEntityWithKey(ctx, "Carol")
+	statement := datastore.CreateStatement(ctx, "The sky is blue")
+
+	now := time.Now()
+	active := createAssertion(t, ctx, statement, alice, assertions.IsTrue, 1.0, validBetween(now.Add(-time.Hour), now.Add(time.Hour)))
+	createAssertion(t, ctx, statement, bob, assertions.IsFalse, 1.0, validBetween(now.Add(time.Hour), now.Add(2*time.Hour)))
+	createAssertion(t, ctx, carol, alice, assertions.IsTrusted, 1.0, validBetween(now.Add(-2*time.Hour), now.Add(-time.Hour)))
+
+	score, _ := NewWeightedModel(datastore.ActiveDataStore).Score(ctx, statement, NewRoots(alice, bob))
+	if len(score.Evidence) != 1 || !score.Evidence[0].Assertion.Equals(active.Uri()) {
+		t.Errorf("Only the active assertion should be evidence: %v", score.Evidence)
+	}
+
+	network := NewNetwork(ctx, datastore.ActiveDataStore, NewRoots(alice))
+	if network.WeightOf(carol) > 0 {
+		t.Error("Trust should not pass through an expired assertion")
+	}
+}
@@ -0,0 +1,62 @@
+package trust
+
+import (
+	"context"
+
+	"silvatek.uk/trustedassertions/internal/assertions"
+	refs "silvatek.uk/trustedassertions/internal/references"
+)
+
+// WeightedModel is the default trust model.
+//
+// Each assertion by an entity in the trust network is treated as an estimate of the likelihood
+// that the statement is true, based on its category and confidence. The score is the average of
+// those estimates, weighted by the network's trust in each issuer.
+type WeightedModel struct {
+	resolver assertions.Resolver
+}
+
+func NewWeightedModel(resolver assertions.Resolver) TrustModel {
+	return &WeightedModel{resolver: resolver}
+}
+
+func (m *WeightedModel) Name() string {
+	return "weighted"
+}
+
+func (m *WeightedModel) Score(ctx context.Context, statement refs.HashUri, roots Roots) (Score, error) {
+	return m.ScoreIn(ctx, statement, NewNetwork(ctx, m.resolver, roots))
+}
+
+func (m *WeightedModel) ScoreIn(ctx context.Context, statement refs.HashUri, network *Network) (Score, error) {
+	score, err := gatherEvidence(ctx, m.resolver, statement, network)
+	score.Model = m.Name()
+	if err != nil || !score.HasEvidence() {
+		return score, err
+	}
+
+	var total float64
+	for _, evidence := range score.Evidence {
+		likelihood, _ := likelihoodOf(evidence.Category, evidence.Confidence)
+		total += evidence.Weight * likelihood
+	}
+	score.Value = total / score.Weight
+
+	return score, nil
+}
+
+// Converts an assertion category and confidence into the likelihood that the statement is true.
+//
+// An assertion with no confidence is neutral (0.5), while a fully confident assertion is
+// either certainly true (1.0) or certainly false (0.0).
+func likelihoodOf(category assertions.AssertionType, confidence float64) (float64, bool) {
+	confidence = min(max(confidence, 0.0), 1.0)
+	switch category.Effect() {
+	case assertions.Supports:
+		return 0.5 + confidence/2, true
+	case assertions.Opposes:
+		return 0.5 - confidence/2, true
+	default:
+		return 0.5, false
+	}
+}
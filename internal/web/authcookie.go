@@ -20,7 +20,7 @@ func SetAuthCookie(userId string, w http.ResponseWriter) {
 }
 
 func MakeAuthCookie(userId string) *http.Cookie {
-	jwt, _ := auth.MakeUserJwt(userId, userJwtKey)
+	jwt, _ := auth.MakeUserJwt(userId, auth.UserJwtKey)
 	expiration := time.Now().Add(2 * time.Hour)
 	cookie := http.Cookie{Name: "auth", Path: "/", Value: jwt, Expires: expiration, SameSite: http.SameSiteStrictMode}
 	return &cookie
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -33,15 +34,11 @@ var ErrorRegistering = AppError{ErrorCode: RegistrationError + 6, UserMessage: "
 
 var RegistrationErrors = []AppError{ErrorRegCode, ErrorPasswordMismatch, ErrorBadUsername, ErrorUserExists, ErrorWeakPassword, ErrorRegistering}
 
-var userJwtKey []byte
-
 func addAuthHandlers(r *mux.Router) {
 	r.HandleFunc("/web/login", LoginWebHandler)
 	r.HandleFunc("/web/logout", LogoutWebHandler)
 	r.HandleFunc("/web/register", RegisterWebHandler)
 	r.HandleFunc("/web/profile", ProfileWebHandler)
-
-	userJwtKey = auth.MakeJwtKey()
 }
 
 func nameOnly(username string) string {
@@ -55,19 +52,7 @@ func nameOnly(username string) string {
 
 // Returns the name of the currently authenticated user, or an empty string.
 func authUsername(r *http.Request) string {
-	cookie, err := r.Cookie("auth")
-	if err != nil {
-		return ""
-	}
-	if cookie.Value == "" {
-		return ""
-	}
-	userName, err := auth.ParseUserJwt(cookie.Value, userJwtKey)
-	if err != nil {
-		log.Errorf("Error parsing user JWT: %v", err)
-		return ""
-	}
-	return userName
+	return auth.RequestUsername(r)
 }
 
 func LoginWebHandler(w http.ResponseWriter, r *http.Request) {
@@ -234,6 +219,18 @@ func ProfileWebHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.Method == "POST" {
+		r.ParseForm()
+		if appErr := updateTrustRoots(ctx, &user, r.Form); appErr != nil {
+			HandleError(ctx, *appErr, w, r)
+			return
+		}
+		datastore.ActiveDataStore.StoreUser(ctx, user)
+
+		http.Redirect(w, r, "/web/profile", http.StatusSeeOther)
+		return
+	}
+
 	signers := make([]entities.Entity, len(user.KeyRefs))
 	for n, keyRef := range user.KeyRefs {
 		keyUri := references.UriFromString(keyRef.KeyId)
@@ -241,15 +238,64 @@ func ProfileWebHandler(w http.ResponseWriter, r *http.Request) {
 		signers[n] = entity
 	}
 
+	roots := make([]trustRootView, len(user.TrustRoots))
+	for n, root := range user.TrustRoots {
+		rootUri := references.UriFromString(root.Entity)
+		entity, _ := datastore.ActiveDataStore.FetchEntity(ctx, rootUri)
+		roots[n] = trustRootView{Uri: rootUri, Name: entity.CommonName, Weight: root.Weight}
+	}
+
 	data := struct {
-		UserName string
-		User     auth.User
-		Entities []entities.Entity
+		UserName   string
+		User       auth.User
+		Entities   []entities.Entity
+		TrustRoots []trustRootView
 	}{
-		UserName: username,
-		User:     user,
-		Entities: signers,
+		UserName:   username,
+		User:       user,
+		Entities:   signers,
+		TrustRoots: roots,
 	}
 
 	RenderWebPage(ctx, "viewprofile", data, nil, w, r)
 }
+
+// A trusted root entity as shown on the user's profile page.
+type trustRootView struct {
+	Uri    references.HashUri
+	Name   string
+	Weight float64
+}
+
+// Adds or removes one of the user's trusted root entities, based on the submitted profile form.
+func updateTrustRoots(ctx context.Context, user *auth.User, form url.Values) *AppError {
+	entityUri := references.UriFromString(strings.TrimSpace(form.Get("entity")))
+	if !entityUri.HasType() {
+		entityUri = entityUri.WithType("entity")
+	}
+	if entityUri.Kind() != "entity" {
+		err := ErrorTrustRoot.instance("Trust root is not an entity: " + entityUri.String())
+		return &err
+	}
+
+	switch form.Get("action") {
+	case "addroot":
+		weight, err := strconv.ParseFloat(form.Get("weight"), 64)
+		if err != nil || weight <= 0 || weight > 1 {
+			appErr := ErrorTrustRoot.instance(fmt.Sprintf("Trust root weight not valid: %s", form.Get("weight")))
+			return &appErr
+		}
+		if _, err := datastore.ActiveDataStore.FetchEntity(ctx, entityUri); err != nil {
+			appErr := ErrorTrustRoot.instance("Trust root entity not found: " + entityUri.String())
+			return &appErr
+		}
+		user.AddTrustRoot(entityUri.String(), weight)
+	case "removeroot":
+		user.RemoveTrustRoot(entityUri.String())
+	default:
+		err := ErrorTrustRoot.instance("Unknown profile action: " + form.Get("action"))
+		return &err
+	}
+
+	return nil
+}
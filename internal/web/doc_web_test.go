@@ -2,8 +2,12 @@ package web
 
 import (
 	"context"
+	"net/url"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
+	"silvatek.uk/trustedassertions/internal/assertions"
 	"silvatek.uk/trustedassertions/internal/datastore"
 )
 
@@ -17,3 +21,93 @@ func TestViewDoc(t *testing.T) {
 	page := wt.GetPage("/web/documents/" + docHash)
 	page.AssertHtmlQuery("h2", "View Document")
 }
+
+func TestViewDocTrust(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	docs, _ := datastore.ActiveDataStore.Search(context.Background(), "GL93J73C")
+
+	page := wt.GetPage("/web/documents/" + docs[0].Uri.Hash())
+	page.AssertHtmlQuery("#docscores", "The universe exists")
+	page.AssertHtmlQuery("#docscores", "no assertions from trusted entities")
+}
+
+func TestNewSignedDocument(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	docs, _ := datastore.ActiveDataStore.Search(context.Background(), "GL93J73C")
+	page := wt.GetPage("/web/documents/" + docs[0].Uri.Hash())
+	page.AssertHtmlQuery("#authorbadge", "Author not verified")
+
+	content := `<document><metadata><title>Signed document</title></metadata><section><paragraph><span>Signed text</span></paragraph></section></document>`
+	page = wt.PostFormData("/web/newdocument", url.Values{"document": {content}, "sign_as": {user.KeyRefs[0].KeyId}})
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#title", "Signed document")
+	page.AssertHtmlQuery("#author", "Signing entity")
+	page.AssertHtmlQuery("#authorbadge", "Verified author")
+
+	// Only the author can sign a document that names them
+	content = `<document><metadata><author entity="hash://sha256/177ed36580cf1ed395e1d0d3a7709993ac1599ee844dc4cf5b9573a1265df2db?type=entity">Mr Tester</author><title>Forged</title></metadata></document>`
+	page = wt.PostFormData("/web/newdocument", url.Values{"document": {content}, "sign_as": {user.KeyRefs[0].KeyId}})
+	page.AssertHtmlQuery("#message", "Document author must be the signing entity")
+
+	content = `<document><metadata><title>Vague</title></metadata><section><paragraph><span assertion="IsTrue">Unsure</span></paragraph></section></document>`
+	page = wt.PostFormData("/web/newdocument", url.Values{"document": {content}, "sign_as": {user.KeyRefs[0].KeyId}})
+	page.AssertHtmlQuery("#message", "Document content not valid")
+
+	// Once the author declares their key compromised from before the signing, the signature no longer counts
+	values := url.Values{
+		"assertion_type": {"IsCompromised"},
+		"since":          {time.Now().UTC().Add(-time.Hour).Format("2006-01-02T15:04")},
+		"confidence":     {"1.0"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	wt.PostFormData(DefaultEntityUri.WebPath()+"/addassertion", values).AssertSuccessResponse()
+	signed, _ := datastore.ActiveDataStore.Search(context.Background(), "Signed text")
+	if len(signed) == 0 {
+		t.Fatal("Signed document not found")
+	}
+	page = wt.GetPage("/web/documents/" + signed[0].Uri.Hash())
+	page.AssertHtmlQuery("#authorbadge", "Author not verified")
+}
+
+func TestDocumentAuthorCompromisedByOthers(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+	ctx := context.Background()
+
+	authorUri := datastore.CreateEntityWithKey(ctx, "Document author")
+	content := `<document><metadata><title>Authored document</title></metadata><section><paragraph><span>Authored text</span></paragraph></section></document>`
+	doc, err := datastore.CreateDocumentAndAssertions(ctx, content, authorUri)
+	if err != nil {
+		t.Fatalf("Error creating document: %v", err)
+	}
+	page := wt.GetPage("/web/documents/" + doc.Uri().Hash())
+	page.AssertHtmlQuery("#authorbadge", "Verified author")
+
+	// A declaration by an entity outside the viewer's trust network does not affect the author
+	strangerUri := datastore.CreateEntityWithKey(ctx, "Stranger")
+	strangerKey, _ := datastore.FetchPrivateKey(strangerUri)
+	declaration := assertions.NewAssertion(assertions.IsCompromised)
+	declaration.Subject = authorUri.String()
+	declaration.Issuer = strangerUri.String()
+	declaration.Since = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	if _, err := datastore.CreateSignedAssertion(ctx, declaration, strangerKey); err != nil {
+		t.Fatalf("Error declaring compromise: %v", err)
+	}
+	page = wt.GetPage("/web/documents/" + doc.Uri().Hash())
+	page.AssertHtmlQuery("#authorbadge", "Verified author")
+
+	// Once a trusted root declares the author's key compromised from before the signing, the signature no longer counts
+	values := url.Values{
+		"assertion_type": {"IsCompromised"},
+		"since":          {time.Now().UTC().Add(-time.Hour).Format("2006-01-02T15:04")},
+		"confidence":     {"1.0"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	wt.PostFormData(authorUri.WebPath()+"/addassertion", values).AssertSuccessResponse()
+	page = wt.GetPage("/web/documents/" + doc.Uri().Hash())
+	page.AssertHtmlQuery("#authorbadge", "Author not verified")
+}
@@ -1,36 +1,119 @@
 package web
 
 import (
+	"context"
+	goerrors "errors"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"silvatek.uk/trustedassertions/internal/appcontext"
+	"silvatek.uk/trustedassertions/internal/assertions"
 	"silvatek.uk/trustedassertions/internal/auth"
 	"silvatek.uk/trustedassertions/internal/datastore"
 	"silvatek.uk/trustedassertions/internal/docs"
+	"silvatek.uk/trustedassertions/internal/entities"
 	ref "silvatek.uk/trustedassertions/internal/references"
+	"silvatek.uk/trustedassertions/internal/trust"
 )
 
 func ViewDocumentWebHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := appcontext.NewWebContext(r)
 	key := mux.Vars(r)["hash"]
-	document, _ := datastore.ActiveDataStore.FetchDocument(ctx, ref.MakeUri(key, "document"))
+	uri := ref.MakeUri(key, "document")
+	document, _ := datastore.ActiveDataStore.FetchDocument(ctx, uri)
+	network := trustNetworkFor(ctx, r)
+
+	authorError := ""
+	if err := verifyAuthor(ctx, document, network); err != nil {
+		authorError = err.Error()
+	}
 
 	data := struct {
-		Doc       docs.Document
-		Title     string
-		DocHtml   string
-		AuthorUri ref.HashUri
+		Doc         docs.Document
+		Hash        string
+		Title       string
+		DocHtml     string
+		AuthorUri   ref.HashUri
+		AuthorError string
+		Claims      []documentClaim
+		Superseded  *supersession
 	}{
-		Doc:       document,
-		Title:     document.Summary(),
-		DocHtml:   document.ToHtml(),
-		AuthorUri: ref.UriFromString(document.Metadata.Author.Entity),
+		Doc:         document,
+		Hash:        uri.Hash(),
+		Title:       document.Summary(),
+		DocHtml:     document.ToHtml(),
+		AuthorUri:   ref.UriFromString(document.Metadata.Author.Entity),
+		AuthorError: authorError,
+		Claims:      documentClaims(ctx, document, trustModelFor(ctx, r), network),
+		Superseded:  supersededBy(ctx, uri, network),
 	}
 
 	RenderWebPage(ctx, "viewdocument", data, nil, w, r)
 }
 
+// Checks that a document was signed by the entity it names as its author, that the author's certificate
+// was valid when the document was signed, and that neither the author nor anyone trusted in the network had
+// declared its key compromised by then.
+// Documents signed without recording the signing time can only have their signature checked.
+func verifyAuthor(ctx context.Context, document docs.Document, network *trust.Network) error {
+	if !document.IsSigned() {
+		return docs.ErrUnsigned
+	}
+	author, err := datastore.ActiveDataStore.FetchEntity(ctx, ref.UriFromString(document.Metadata.Author.Entity))
+	if err != nil {
+		return err
+	}
+	if err := document.VerifySignature(author); err != nil {
+		return err
+	}
+
+	signed := document.SignedAt()
+	if signed.IsZero() {
+		return nil
+	}
+	if err := author.CheckValidAt(signed); err != nil {
+		return err
+	}
+	if compromise, found := trust.CompromiseOf(ctx, datastore.ActiveDataStore, author.Uri(), network); found &&
+		!signed.Before(compromise.Since) {
+		return assertions.ErrCompromised
+	}
+	return nil
+}
+
+// A statement asserted in a document, with its trust score.
+type documentClaim struct {
+	Statement ref.HashUri
+	Text      string
+	Score     trust.Score
+}
+
+// Scores the statements that are the subjects of the assertions referenced by a document.
+func documentClaims(ctx context.Context, document docs.Document, model trust.TrustModel, network *trust.Network) []documentClaim {
+	claims := make([]documentClaim, 0)
+	for _, uri := range document.References() {
+		if !assertions.IsAssertionKind(uri.Kind()) {
+			continue
+		}
+		assertion, err := datastore.ActiveDataStore.FetchAssertion(ctx, uri)
+		if err != nil {
+			log.ErrorfX(ctx, "Error fetching document assertion %s: %v", uri, err)
+			continue
+		}
+		subjectUri := assertions.TypedUri(ctx, datastore.ActiveDataStore, ref.UriFromString(assertion.Subject))
+		if subjectUri.Kind() != "statement" {
+			continue
+		}
+		statement, _ := datastore.ActiveDataStore.FetchStatement(ctx, subjectUri)
+		claims = append(claims, documentClaim{
+			Statement: subjectUri,
+			Text:      statement.Content(),
+			Score:     trustScore(ctx, model, subjectUri, network),
+		})
+	}
+	return claims
+}
+
 func NewDocumentWebHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := appcontext.NewWebContext(r)
 
@@ -68,7 +151,19 @@ func NewDocumentWebHandler(w http.ResponseWriter, r *http.Request) {
 		docxml := r.Form.Get("document")
 
 		doc, err := datastore.CreateDocumentAndAssertions(ctx, docxml, keyUri)
-		if err != nil {
+		if goerrors.Is(err, datastore.ErrDocumentAuthor) {
+			HandleError(ctx, ErrorDocumentAuthor.instance(err.Error()), w, r)
+			return
+		} else if goerrors.Is(err, datastore.ErrInvalidDocument) {
+			HandleError(ctx, ErrorDocumentContent.instance(err.Error()), w, r)
+			return
+		} else if goerrors.Is(err, assertions.ErrCompromised) {
+			HandleError(ctx, ErrorKeyCompromised.instance(err.Error()), w, r)
+			return
+		} else if goerrors.Is(err, entities.ErrCertificateNotValid) {
+			HandleError(ctx, ErrorCertificateExpired.instance(err.Error()), w, r)
+			return
+		} else if err != nil {
 			HandleError(ctx, ErrorMakeDocument.instance("Error creating new document"), w, r)
 			return
 		}
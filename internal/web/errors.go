@@ -24,6 +24,10 @@ const FetchError = 1000
 
 var ErrorEntityFetch = AppError{ErrorCode: FetchError + 1, UserMessage: "Error retrieving entity"}
 var ErrorAssertionFetch = AppError{ErrorCode: FetchError + 2, UserMessage: "Error retrieving assertion"}
+var ErrorStatementFetch = AppError{ErrorCode: FetchError + 3, UserMessage: "Error retrieving statement", HttpCode: 404}
+var ErrorAssertionCompromised = AppError{ErrorCode: FetchError + 4, UserMessage: "Assertion was issued after its signing key was compromised", HttpCode: 403}
+var ErrorAssertionAudience = AppError{ErrorCode: FetchError + 5, UserMessage: "Assertion is not for a supported protocol version", HttpCode: 403}
+var ErrorAssertionCertificate = AppError{ErrorCode: FetchError + 6, UserMessage: "Assertion was issued when its signing certificate was not valid", HttpCode: 403}
 
 const UpdateError = 2000
 
@@ -31,6 +35,23 @@ var ErrorMakeAssertion = AppError{ErrorCode: UpdateError + 2, UserMessage: "Erro
 var ErrorKeyFetch = AppError{ErrorCode: UpdateError + 3, UserMessage: "Error fetching key"}
 var ErrorKeyAccess = AppError{ErrorCode: UpdateError + 4, UserMessage: "Error accessing key", HttpCode: 403}
 var ErrorMakeDocument = AppError{ErrorCode: UpdateError + 5, UserMessage: "Error making document"}
+var ErrorAssertionType = AppError{ErrorCode: UpdateError + 6, UserMessage: "Assertion type not valid for subject", HttpCode: 400}
+var ErrorTrustRoot = AppError{ErrorCode: UpdateError + 7, UserMessage: "Trusted entity not valid", HttpCode: 400}
+var ErrorAssertionObject = AppError{ErrorCode: UpdateError + 8, UserMessage: "Assertion object not valid", HttpCode: 400}
+var ErrorCompromisedSince = AppError{ErrorCode: UpdateError + 9, UserMessage: "Compromise time not valid", HttpCode: 400}
+var ErrorKeyCompromised = AppError{ErrorCode: UpdateError + 10, UserMessage: "Signing key has been declared compromised", HttpCode: 403}
+var ErrorAssertionBasis = AppError{ErrorCode: UpdateError + 11, UserMessage: "Assertion basis not valid", HttpCode: 400}
+var ErrorAssertionValidity = AppError{ErrorCode: UpdateError + 12, UserMessage: "Assertion validity period not valid", HttpCode: 400}
+var ErrorAssertionRetraction = AppError{ErrorCode: UpdateError + 13, UserMessage: "Only the issuer of an assertion can retract it", HttpCode: 403}
+var ErrorKeyAlgorithm = AppError{ErrorCode: UpdateError + 14, UserMessage: "Key algorithm not supported", HttpCode: 400}
+var ErrorMakeEntity = AppError{ErrorCode: UpdateError + 15, UserMessage: "Error making entity"}
+var ErrorCertificateExpired = AppError{ErrorCode: UpdateError + 16, UserMessage: "Signing certificate has expired or is not yet valid", HttpCode: 403}
+var ErrorRenewEntity = AppError{ErrorCode: UpdateError + 17, UserMessage: "Error renewing entity"}
+var ErrorRotateKey = AppError{ErrorCode: UpdateError + 18, UserMessage: "Error rotating key"}
+var ErrorSignedAssertion = AppError{ErrorCode: UpdateError + 19, UserMessage: "Signed assertion not valid", HttpCode: 400}
+var ErrorAssertionFormat = AppError{ErrorCode: UpdateError + 20, UserMessage: "Assertion format not supported", HttpCode: 400}
+var ErrorDocumentAuthor = AppError{ErrorCode: UpdateError + 21, UserMessage: "Document author must be the signing entity", HttpCode: 403}
+var ErrorDocumentContent = AppError{ErrorCode: UpdateError + 22, UserMessage: "Document content not valid", HttpCode: 400}
 
 var ErrorFakeTest = AppError{ErrorCode: 9999, UserMessage: "Fake error for testing"}
 
@@ -2,15 +2,18 @@ package web
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
+	goerrors "errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"text/template"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/csrf"
 	"github.com/gorilla/mux"
 	"silvatek.uk/trustedassertions/internal/appcontext"
@@ -21,7 +24,7 @@ import (
 	"silvatek.uk/trustedassertions/internal/entities"
 	"silvatek.uk/trustedassertions/internal/logging"
 	ref "silvatek.uk/trustedassertions/internal/references"
-	"silvatek.uk/trustedassertions/internal/statements"
+	"silvatek.uk/trustedassertions/internal/trust"
 )
 
 var TemplateDir string
@@ -36,15 +39,25 @@ func AddHandlers(r *mux.Router) {
 	r.HandleFunc("/web", HomeRedirectWebHandler)
 	r.HandleFunc("/web/home", HomeWebHandler)
 	r.HandleFunc("/web/statements/{hash}", ViewStatementWebHandler)
+	r.HandleFunc("/web/statements/{hash}/trust", StatementTrustWebHandler)
+	r.HandleFunc("/web/contested", ContestedWebHandler)
 	r.HandleFunc("/web/entities/{hash}", ViewEntityWebHandler)
 	r.HandleFunc("/web/assertions/{hash}", ViewAssertionWebHandler)
+	r.HandleFunc("/web/coseassertions/{hash}", ViewAssertionWebHandler)
 	r.HandleFunc("/web/documents/{hash}", ViewDocumentWebHandler)
 	r.HandleFunc("/web/broken", ErrorTestHandler)
 	r.HandleFunc("/web/error", ErrorPageHandler)
 	r.HandleFunc("/web/newstatement", NewStatementWebHandler)
 	r.HandleFunc("/web/newentity", NewEntityWebHandler)
 	r.HandleFunc("/web/newdocument", NewDocumentWebHandler)
+	r.HandleFunc("/web/submitassertion", SubmitAssertionWebHandler)
 	r.HandleFunc("/web/statements/{hash}/addassertion", AddStatementAssertionWebHandler)
+	r.HandleFunc("/web/entities/{hash}/addassertion", AddEntityAssertionWebHandler)
+	r.HandleFunc("/web/entities/{hash}/renew", RenewEntityWebHandler)
+	r.HandleFunc("/web/entities/{hash}/rotate", RotateEntityWebHandler)
+	r.HandleFunc("/web/documents/{hash}/addassertion", AddDocumentAssertionWebHandler)
+	r.HandleFunc("/web/assertions/{hash}/addassertion", AddAssertionAssertionWebHandler)
+	r.HandleFunc("/web/coseassertions/{hash}/addassertion", AddCoseAssertionAssertionWebHandler)
 	r.HandleFunc("/web/search", SearchWebHandler)
 	r.HandleFunc("/web/share", SharePageWebHandler)
 	r.HandleFunc("/web/qrcode", qrCodeGenerator)
@@ -180,26 +193,35 @@ func ViewStatementWebHandler(w http.ResponseWriter, r *http.Request) {
 	key := mux.Vars(r)["hash"]
 	statement, _ := datastore.ActiveDataStore.FetchStatement(ctx, ref.MakeUri(key, "statement"))
 
-	refs, _ := datastore.ActiveDataStore.FetchRefs(ctx, statement.Uri())
-	enrichReferencesTo(ctx, &statement, refs)
+	network := trustNetworkFor(ctx, r)
+	variants := statementVariants(ctx, statement.Uri(), network)
 
 	data := struct {
 		Uri        ref.HashUri
 		ShortUri   string
 		Content    string
 		ApiLink    string
-		References []ref.Reference
+		References []clusterReference
+		Variants   []statementVariant
+		Score      trust.Score
+		Contest    trust.Contest
+		Superseded *supersession
 	}{
 		Uri:        statement.Uri(),
 		ShortUri:   statement.Uri().Short(),
 		Content:    statement.Content(),
 		ApiLink:    statement.Uri().ApiPath(),
-		References: refs,
+		References: clusterReferences(ctx, &statement, variants),
+		Variants:   variants,
+		Score:      trustScore(ctx, trustModelFor(ctx, r), statement.Uri(), network),
+		Contest:    statementContest(ctx, statement.Uri()),
+		Superseded: supersededBy(ctx, statement.Uri(), network),
 	}
 
 	menu := []PageMenuItem{
 		{Text: "Raw", Target: statement.Uri().ApiPath()},
 		{Text: "Share", Target: "/web/share?hash=" + statement.Uri().Hash() + "&type=statement"},
+		{Text: "Trust", Target: statement.Uri().WebPath() + "/trust"},
 	}
 
 	RenderWebPage(ctx, "viewstatement", data, menu, w, r)
@@ -209,7 +231,7 @@ func enrichReferencesTo(ctx context.Context, target ref.Referenceable, refs []re
 	var wg sync.WaitGroup
 
 	for n, reference := range refs {
-		if reference.Summary == "" {
+		if reference.Summary == "" || (reference.Category == "" && assertions.IsAssertionKind(reference.Source.Kind())) {
 			wg.Add(1)
 			go func(ref *ref.Reference) {
 				// Construct a summary for the reference
@@ -227,12 +249,25 @@ func enrichReferencesTo(ctx context.Context, target ref.Referenceable, refs []re
 	wg.Wait()
 }
 
+// How long before it was timestamped an assertion can claim to have been issued before it is flagged as backdated.
+const backdatingThreshold = time.Hour
+
 func ViewAssertionWebHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := appcontext.NewWebContext(r)
 
 	key := mux.Vars(r)["hash"]
 	uri := ref.MakeUri(key, "assertion")
-	assertion, _ := datastore.ActiveDataStore.FetchAssertion(ctx, uri)
+	assertion, err := datastore.ActiveDataStore.FetchAssertion(ctx, uri)
+	if goerrors.Is(err, assertions.ErrCompromised) {
+		HandleError(ctx, ErrorAssertionCompromised.instance("Assertion "+uri.String()+" issued after key compromise"), w, r)
+		return
+	} else if goerrors.Is(err, assertions.ErrUnsupportedAudience) {
+		HandleError(ctx, ErrorAssertionAudience.instance("Assertion "+uri.String()+": "+err.Error()), w, r)
+		return
+	} else if goerrors.Is(err, entities.ErrCertificateNotValid) {
+		HandleError(ctx, ErrorAssertionCertificate.instance("Assertion "+uri.String()+": "+err.Error()), w, r)
+		return
+	}
 
 	issuerUri := ref.UriFromString(assertion.Issuer)
 	if !issuerUri.HasType() {
@@ -241,36 +276,62 @@ func ViewAssertionWebHandler(w http.ResponseWriter, r *http.Request) {
 
 	issuer, _ := datastore.ActiveDataStore.FetchEntity(ctx, issuerUri)
 
-	subjectUri := ref.UriFromString(assertion.Subject)
-	if !subjectUri.HasType() {
-		subjectUri = subjectUri.WithType("statement")
-	}
-
-	subject, _ := datastore.ActiveDataStore.FetchStatement(ctx, subjectUri)
+	subjectUri := assertions.TypedUri(ctx, datastore.ActiveDataStore, ref.UriFromString(assertion.Subject))
+	subject, _ := datastore.ActiveDataStore.Fetch(ctx, subjectUri)
 
 	refs, _ := datastore.ActiveDataStore.FetchRefs(ctx, uri)
 	enrichReferencesTo(ctx, &assertion, refs)
 
+	network := trustNetworkFor(ctx, r)
+	var subjectScore *trust.Score
+	if subjectUri.Kind() == "statement" {
+		score := trustScore(ctx, trustModelFor(ctx, r), subjectUri, network)
+		subjectScore = &score
+	}
+
+	annotations := assertion.Annotate(ctx, datastore.ActiveDataStore)
+
+	compromise := compromiseOf(ctx, issuerUri, network)
+	if compromise != nil && !compromise.Covers(assertion) {
+		compromise = nil
+	}
+
 	data := struct {
-		Uri         string
-		ShortUri    string
-		Assertion   assertions.Assertion
-		IssuerLink  string
-		IssuerName  string
-		SubjectLink string
-		SubjectText string
-		ApiLink     string
-		References  []ref.Reference
+		Uri          string
+		Hash         string
+		ShortUri     string
+		Assertion    assertions.Assertion
+		IssuerLink   string
+		IssuerName   string
+		IssuerTrust  *trust.Node
+		SubjectLink  string
+		SubjectText  string
+		SubjectScore *trust.Score
+		Compromise   *trust.Compromise
+		Retraction   *assertions.Assertion
+		Timestamp    *assertions.Assertion
+		Backdated    bool
+		Basis        []basisView
+		ApiLink      string
+		References   []ref.Reference
 	}{
-		Uri:         assertion.Uri().String(),
-		ShortUri:    assertion.Uri().Short(),
-		Assertion:   assertion,
-		ApiLink:     assertion.Uri().ApiPath(),
-		IssuerLink:  issuerUri.WebPath(),
-		IssuerName:  issuer.CommonName,
-		SubjectLink: subjectUri.WebPath(),
-		SubjectText: subject.Content(),
-		References:  refs,
+		Uri:          assertion.Uri().String(),
+		Hash:         assertion.Uri().Hash(),
+		ShortUri:     assertion.Uri().Short(),
+		Assertion:    assertion,
+		ApiLink:      assertion.Uri().ApiPath(),
+		IssuerLink:   issuerUri.WebPath(),
+		IssuerName:   issuer.CommonName,
+		IssuerTrust:  entityTrust(network, issuerUri),
+		SubjectLink:  subjectUri.WebPath(),
+		SubjectText:  subjectText(ctx, subject),
+		SubjectScore: subjectScore,
+		Compromise:   compromise,
+		Retraction:   annotations.Retraction,
+		Timestamp:    annotations.Timestamp,
+		Backdated:    assertion.TimestampDelay() > backdatingThreshold,
+		Basis:        basisOf(ctx, assertion),
+		References:   refs,
 	}
 
 	menu := []PageMenuItem{
@@ -281,6 +342,37 @@ func ViewAssertionWebHandler(w http.ResponseWriter, r *http.Request) {
 	RenderWebPage(ctx, "viewassertion", data, menu, w, r)
 }
 
+// Returns the text describing the subject of an assertion. Assertions have no text of their own, so they are summarised instead.
+func subjectText(ctx context.Context, subject ref.Referenceable) string {
+	if assertion, ok := subject.(*assertions.Assertion); ok {
+		if assertion.RegisteredClaims == nil {
+			return ""
+		}
+		return assertions.SummariseAssertion(ctx, *assertion, nil, datastore.ActiveDataStore)
+	}
+	return subject.TextContent()
+}
+
+// An assertion cited in support of another, as listed on the page for the citing assertion.
+type basisView struct {
+	Uri     ref.HashUri
+	Summary string
+}
+
+// Returns the assertions that an assertion cites as its basis, with a summary of each.
+func basisOf(ctx context.Context, assertion assertions.Assertion) []basisView {
+	basis := make([]basisView, 0, len(assertion.Basis))
+	for _, b := range assertion.Basis {
+		uri := ref.UriFromString(b)
+		view := basisView{Uri: uri, Summary: "Unverified assertion"}
+		if cited, err := datastore.ActiveDataStore.FetchAssertion(ctx, uri); err == nil {
+			view.Summary = assertions.SummariseAssertion(ctx, cited, nil, datastore.ActiveDataStore)
+		}
+		basis = append(basis, view)
+	}
+	return basis
+}
+
 func ViewEntityWebHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := appcontext.NewWebContext(r)
 
@@ -296,20 +388,64 @@ func ViewEntityWebHandler(w http.ResponseWriter, r *http.Request) {
 	refs, _ := datastore.ActiveDataStore.FetchRefs(ctx, entity.Uri())
 	enrichReferencesTo(ctx, &entity, refs)
 
+	network := trustNetworkFor(ctx, r)
+
+	var parent *entityView
+	var chainError string
+	if !entity.IsSelfSigned() {
+		parent = &entityView{Uri: entity.Parent, Name: entity.Parent.Short()}
+		if organisation, err := datastore.ActiveDataStore.FetchEntity(ctx, entity.Parent); err == nil {
+			parent.Name = organisation.CommonName
+		}
+		if _, err := assertions.IssuerChain(ctx, datastore.ActiveDataStore, entity); err != nil {
+			chainError = err.Error()
+		}
+	}
+
 	data := struct {
-		Uri        string
-		ShortUri   string
-		CommonName string
-		ApiLink    string
-		PublicKey  string
-		References []ref.Reference
+		Uri         string
+		Hash        string
+		ShortUri    string
+		CommonName  string
+		ApiLink     string
+		PublicKey   string
+		Algorithm   entities.KeyAlgorithm
+		Algorithms  []entities.KeyAlgorithm
+		CanReissue  bool
+		Trust       *trust.Node
+		Superseded  *supersession
+		Compromise  *trust.Compromise
+		Parent      *entityView
+		ChainError  string
+		Members     []entityView
+		Issued      time.Time
+		Expires     time.Time
+		Expiry      *certificateExpiry
+		Successor   *successionView
+		Predecessor *successionView
+		References  []ref.Reference
 	}{
-		Uri:        uri.String(),
-		ShortUri:   uri.Short(),
-		CommonName: entity.CommonName,
-		PublicKey:  fmt.Sprintf("%v", entity.PublicKey),
-		ApiLink:    uri.ApiPath(),
-		References: refs,
+		Uri:         uri.String(),
+		Hash:        uri.Hash(),
+		ShortUri:    uri.Short(),
+		CommonName:  entity.CommonName,
+		PublicKey:   fmt.Sprintf("%v", entity.PublicKey),
+		Algorithm:   entity.KeyAlgorithm(),
+		Algorithms:  entities.KeyAlgorithms(),
+		CanReissue:  userCanReissue(ctx, r, uri),
+		ApiLink:     uri.ApiPath(),
+		Trust:       entityTrust(network, uri),
+		Superseded:  supersededBy(ctx, uri, network),
+		Compromise:  compromiseOf(ctx, uri, network),
+		Parent:      parent,
+		ChainError:  chainError,
+		Members:     membersOf(ctx, uri),
+		Issued:      entity.Issued,
+		Expires:     entity.Expires,
+		Expiry:      expiryOf(entity),
+		Successor:   successorOf(ctx, entity),
+		Predecessor: predecessorOf(ctx, entity),
+		References:  refs,
 	}
 
 	menu := []PageMenuItem{
@@ -320,6 +456,59 @@ func ViewEntityWebHandler(w http.ResponseWriter, r *http.Request) {
 	RenderWebPage(ctx, "viewentity", data, menu, w, r)
 }
 
+// An entity related to the one being viewed, such as the organisation that issued its certificate.
+type entityView struct {
+	Uri  ref.HashUri
+	Name string
+}
+
+// A warning that an entity's certificate has expired or will expire soon.
+type certificateExpiry struct {
+	Expires time.Time
+	Expired bool
+}
+
+// Returns a warning if an entity's certificate needs renewing, or nil if it does not.
+func expiryOf(entity entities.Entity) *certificateExpiry {
+	if !entity.NeedsRenewal() {
+		return nil
+	}
+	return &certificateExpiry{Expires: entity.Expires, Expired: entity.IsExpired()}
+}
+
+// Another certificate of the same identity as the entity being viewed, linked by a renewal or key rotation.
+type successionView struct {
+	entityView
+	Rotated bool
+}
+
+// Returns the entity that replaced an entity by renewal or key rotation, or nil if it has not been replaced.
+func successorOf(ctx context.Context, entity entities.Entity) *successionView {
+	succession, found := assertions.SuccessorOf(ctx, datastore.ActiveDataStore, entity)
+	if !found {
+		return nil
+	}
+	return &successionView{entityView{Uri: succession.Successor.Uri(), Name: succession.Successor.CommonName}, succession.Rotated}
+}
+
+// Returns the entity that an entity replaced by renewal or key rotation, or nil if it did not replace one.
+func predecessorOf(ctx context.Context, entity entities.Entity) *successionView {
+	succession, found := assertions.PredecessorOf(ctx, datastore.ActiveDataStore, entity)
+	if !found {
+		return nil
+	}
+	return &successionView{entityView{Uri: succession.Original.Uri(), Name: succession.Original.CommonName}, succession.Rotated}
+}
+
+// Returns the entities that an organisation has issued certificates for.
+func membersOf(ctx context.Context, organisation ref.HashUri) []entityView {
+	members := make([]entityView, 0)
+	for _, member := range assertions.MembersOf(ctx, datastore.ActiveDataStore, organisation) {
+		members = append(members, entityView{Uri: member.Uri(), Name: member.CommonName})
+	}
+	return members
+}
+
 func NewStatementWebHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := appcontext.NewWebContext(r)
 
@@ -367,6 +556,47 @@ func NewStatementWebHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Stores an assertion that was signed elsewhere with the issuer's own key, so that the key need not be
+// held by the server. There is no need to log in, as the signature shows who made the assertion.
+func SubmitAssertionWebHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := appcontext.NewWebContext(r)
+
+	if r.Method == "GET" {
+		RenderWebPage(ctx, "submitassertionform", nil, nil, w, r)
+	} else if r.Method == "POST" {
+		r.ParseForm()
+
+		assertion, err := datastore.StoreSignedAssertion(ctx, r.Form.Get("jwt"))
+		if goerrors.Is(err, assertions.ErrCompromised) {
+			HandleError(ctx, ErrorKeyCompromised.instance(err.Error()), w, r)
+			return
+		} else if goerrors.Is(err, entities.ErrCertificateNotValid) {
+			HandleError(ctx, ErrorCertificateExpired.instance(err.Error()), w, r)
+			return
+		} else if goerrors.Is(err, datastore.ErrInvalidBasis) {
+			HandleError(ctx, ErrorAssertionBasis.instance(err.Error()), w, r)
+			return
+		} else if goerrors.Is(err, datastore.ErrInvalidValidity) {
+			HandleError(ctx, ErrorAssertionValidity.instance(err.Error()), w, r)
+			return
+		} else if goerrors.Is(err, datastore.ErrInvalidRetraction) {
+			HandleError(ctx, ErrorAssertionRetraction.instance(err.Error()), w, r)
+			return
+		} else if goerrors.Is(err, datastore.ErrInvalidCategory) {
+			HandleError(ctx, ErrorAssertionType.instance(err.Error()), w, r)
+			return
+		} else if err != nil {
+			HandleError(ctx, ErrorSignedAssertion.instance(err.Error()), w, r)
+			return
+		}
+
+		// Redirect the user to the assertion
+		http.Redirect(w, r, assertion.Uri().WebPath(), http.StatusSeeOther)
+
+		log.DebugfX(ctx, "Redirecting to %s", assertion.Uri().WebPath())
+	}
+}
+
 func SearchWebHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := appcontext.NewWebContext(r)
 	query := r.URL.Query().Get("query")
@@ -400,32 +630,247 @@ func NewEntityWebHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method == "GET" {
-		RenderWebPage(ctx, "newentityform", user, nil, w, r)
+		data := struct {
+			User             auth.User
+			Algorithms       []entities.KeyAlgorithm
+			DefaultAlgorithm entities.KeyAlgorithm
+		}{
+			User:             user,
+			Algorithms:       entities.KeyAlgorithms(),
+			DefaultAlgorithm: entities.DefaultKeyAlgorithm,
+		}
+		RenderWebPage(ctx, "newentityform", data, nil, w, r)
 	} else if r.Method == "POST" {
 		log.InfofX(ctx, "Creating new entity and signing key")
 		r.ParseForm()
 		commonName := r.Form.Get("commonname")
 		log.DebugfX(ctx, "Common name: %s", commonName)
 
-		privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
-		entity := entities.Entity{CommonName: commonName}
-		entity.MakeCertificate(privateKey)
-
-		datastore.ActiveDataStore.Store(ctx, &entity)
+		algorithm := entities.DefaultKeyAlgorithm
+		if name := r.Form.Get("algorithm"); name != "" {
+			algorithm, err = entities.KeyAlgorithmOf(name)
+			if err != nil {
+				HandleError(ctx, ErrorKeyAlgorithm.instance(err.Error()), w, r)
+				return
+			}
+		}
 
-		datastore.ActiveDataStore.StoreKey(entity.Uri(), entities.PrivateKeyToString(privateKey))
+		// Members of an organisation have certificates issued with the organisation's key, which the user must hold
+		var entityUri ref.HashUri
+		if parent := r.Form.Get("parent"); parent != "" {
+			if !user.HasKey(parent) {
+				HandleError(ctx, ErrorKeyAccess.instance("User does not have access to the key of organisation "+parent), w, r)
+				return
+			}
+			entityUri, err = datastore.CreateMemberEntity(ctx, commonName, algorithm, ref.UriFromString(parent))
+		} else {
+			entityUri, err = datastore.CreateEntityWithAlgorithm(ctx, commonName, algorithm)
+		}
+		if err != nil {
+			HandleError(ctx, ErrorMakeEntity.instance(err.Error()), w, r)
+			return
+		}
 
-		user.AddKeyRef(entity.Uri().Escaped(), entity.CommonName)
+		user.AddKeyRef(entityUri.Escaped(), commonName)
 		datastore.ActiveDataStore.StoreUser(ctx, user)
 
 		// Redirect the user to the assertion
-		http.Redirect(w, r, entity.Uri().WebPath(), http.StatusSeeOther)
+		http.Redirect(w, r, entityUri.WebPath(), http.StatusSeeOther)
+
+		log.Infof("Redirecting to %s", entityUri.WebPath())
+	}
+}
+
+// Renews an entity whose key the user holds, giving the user the key to the renewed entity too.
+func RenewEntityWebHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := appcontext.NewWebContext(r)
+
+	uri := ref.MakeUri(mux.Vars(r)["hash"], "entity")
+	if r.Method != "POST" {
+		http.Redirect(w, r, uri.WebPath(), http.StatusSeeOther)
+		return
+	}
+
+	username := authUsername(r)
+	if username == "" {
+		HandleError(ctx, ErrorNoAuth, w, r)
+		return
+	}
+	user, err := datastore.ActiveDataStore.FetchUser(ctx, username)
+	if err != nil {
+		HandleError(ctx, ErrorUserNotFound.instance("User not found when renewing entity: "+username), w, r)
+		return
+	}
+	if _, appErr := reissueAccess(ctx, user, uri); appErr != nil {
+		HandleError(ctx, *appErr, w, r)
+		return
+	}
+
+	log.InfofX(ctx, "Renewing entity %s", uri)
+	renewedUri, err := datastore.RenewEntity(ctx, uri)
+	if err != nil {
+		HandleError(ctx, ErrorRenewEntity.instance(err.Error()), w, r)
+		return
+	}
+	renewed, _ := datastore.ActiveDataStore.FetchEntity(ctx, renewedUri)
+
+	user.AddKeyRef(renewedUri.Escaped(), renewed.CommonName)
+	datastore.ActiveDataStore.StoreUser(ctx, user)
+
+	http.Redirect(w, r, renewedUri.WebPath(), http.StatusSeeOther)
+}
+
+// Rotates the key of an entity whose key the user holds, replacing the user's reference to the old key
+// with one to the new key.
+func RotateEntityWebHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := appcontext.NewWebContext(r)
+
+	uri := ref.MakeUri(mux.Vars(r)["hash"], "entity")
+	if r.Method != "POST" {
+		http.Redirect(w, r, uri.WebPath(), http.StatusSeeOther)
+		return
+	}
+
+	username := authUsername(r)
+	if username == "" {
+		HandleError(ctx, ErrorNoAuth, w, r)
+		return
+	}
+	user, err := datastore.ActiveDataStore.FetchUser(ctx, username)
+	if err != nil {
+		HandleError(ctx, ErrorUserNotFound.instance("User not found when rotating key: "+username), w, r)
+		return
+	}
+	keyId, appErr := reissueAccess(ctx, user, uri)
+	if appErr != nil {
+		HandleError(ctx, *appErr, w, r)
+		return
+	}
+
+	r.ParseForm()
+	algorithm := entities.DefaultKeyAlgorithm
+	if name := r.Form.Get("algorithm"); name != "" {
+		algorithm, err = entities.KeyAlgorithmOf(name)
+		if err != nil {
+			HandleError(ctx, ErrorKeyAlgorithm.instance(err.Error()), w, r)
+			return
+		}
+	}
 
-		log.Infof("Redirecting to %s", entity.Uri().WebPath())
+	log.InfofX(ctx, "Rotating key of entity %s", uri)
+	rotatedUri, err := datastore.RotateEntity(ctx, uri, algorithm)
+	if err != nil {
+		HandleError(ctx, ErrorRotateKey.instance(err.Error()), w, r)
+		return
 	}
+	rotated, _ := datastore.ActiveDataStore.FetchEntity(ctx, rotatedUri)
+
+	user.ReplaceKeyRef(keyId, rotatedUri.Escaped(), rotated.CommonName)
+	datastore.ActiveDataStore.StoreUser(ctx, user)
+
+	http.Redirect(w, r, rotatedUri.WebPath(), http.StatusSeeOther)
+}
+
+// Checks that the user holds the keys needed to reissue the certificate of an entity, which are the entity's
+// own key and, for a member of an organisation, the organisation's key that the certificate is issued with.
+// Returns the ID of the user's reference to the entity's key.
+func reissueAccess(ctx context.Context, user auth.User, uri ref.HashUri) (string, *AppError) {
+	keyId, found := heldKeyId(user, uri)
+	if !found {
+		appErr := ErrorKeyAccess.instance("User does not have access to the key of entity " + uri.String())
+		return "", &appErr
+	}
+	entity, err := datastore.ActiveDataStore.FetchEntity(ctx, uri)
+	if err != nil {
+		appErr := ErrorEntityFetch.instance("Error fetching entity " + uri.String() + ": " + err.Error())
+		return "", &appErr
+	}
+	if !entity.IsSelfSigned() && !holdsKey(user, entity.Parent) {
+		appErr := ErrorKeyAccess.instance("User does not have access to the key of organisation " + entity.Parent.String())
+		return "", &appErr
+	}
+	return keyId, nil
+}
+
+// Whether the user holds the key of an entity, whichever form of its URI the key reference uses.
+func holdsKey(user auth.User, uri ref.HashUri) bool {
+	_, found := heldKeyId(user, uri)
+	return found
+}
+
+// Returns the ID of the user's reference to the key of an entity, which may use any form of its URI.
+func heldKeyId(user auth.User, uri ref.HashUri) (string, bool) {
+	for _, keyId := range []string{uri.String(), uri.Escaped(), uri.Unadorned()} {
+		if user.HasKey(keyId) {
+			return keyId, true
+		}
+	}
+	return "", false
+}
+
+// Whether the logged in user, if any, holds the keys needed to renew or rotate an entity.
+func userCanReissue(ctx context.Context, r *http.Request, uri ref.HashUri) bool {
+	username := authUsername(r)
+	if username == "" {
+		return false
+	}
+	user, err := datastore.ActiveDataStore.FetchUser(ctx, username)
+	if err != nil {
+		return false
+	}
+	_, appErr := reissueAccess(ctx, user, uri)
+	return appErr == nil
 }
 
 func AddStatementAssertionWebHandler(w http.ResponseWriter, r *http.Request) {
+	addAssertionWebHandler(w, r, "statement")
+}
+
+func AddDocumentAssertionWebHandler(w http.ResponseWriter, r *http.Request) {
+	addAssertionWebHandler(w, r, "document")
+}
+
+func AddEntityAssertionWebHandler(w http.ResponseWriter, r *http.Request) {
+	addAssertionWebHandler(w, r, "entity")
+}
+
+func AddAssertionAssertionWebHandler(w http.ResponseWriter, r *http.Request) {
+	addAssertionWebHandler(w, r, "assertion")
+}
+
+func AddCoseAssertionAssertionWebHandler(w http.ResponseWriter, r *http.Request) {
+	addAssertionWebHandler(w, r, assertions.COSE_KIND)
+}
+
+// Returns the language most preferred by the browser, or the default language if it has no preference.
+func requestLanguage(r *http.Request) string {
+	preferred, _, _ := strings.Cut(r.Header.Get("Accept-Language"), ",")
+	preferred, _, _ = strings.Cut(preferred, ";")
+	preferred = strings.TrimSpace(preferred)
+	if preferred == "" || preferred == "*" {
+		return assertions.DefaultLanguage
+	}
+	return preferred
+}
+
+// The layout of the times entered on the add assertion form, in UTC.
+const formTimeLayout = "2006-01-02T15:04"
+
+// Returns the time entered in a field of the add assertion form, or nil if the field is blank.
+func formTime(r *http.Request, field string) (*jwt.NumericDate, error) {
+	value := r.Form.Get(field)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(formTimeLayout, value)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.NewNumericDate(t), nil
+}
+
+// Handles the form for adding a new assertion about a subject of the specified kind.
+func addAssertionWebHandler(w http.ResponseWriter, r *http.Request, kind string) {
 	ctx := appcontext.NewWebContext(r)
 
 	username := authUsername(r)
@@ -435,31 +880,49 @@ func AddStatementAssertionWebHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	user, err := datastore.ActiveDataStore.FetchUser(ctx, username)
 	if err != nil {
-		HandleError(ctx, ErrorUserNotFound.instance("User not found when making new statement: "+username), w, r)
+		HandleError(ctx, ErrorUserNotFound.instance("User not found when making new assertion: "+username), w, r)
 		return
 	}
 
-	statementHash := mux.Vars(r)["hash"]
+	subjectUri := ref.MakeUri(mux.Vars(r)["hash"], kind)
+	categories := assertions.CategoriesFor(kind)
 
 	if r.Method == "GET" {
-		statement, err := datastore.ActiveDataStore.FetchStatement(ctx, ref.MakeUri(statementHash, "statement"))
+		subject, err := datastore.ActiveDataStore.Fetch(ctx, subjectUri)
 		if err != nil {
-			log.Errorf("Error fetching statement: %v", err)
+			log.Errorf("Error fetching %s: %v", kind, err)
 		} else {
-			log.Debugf("Statement content = %s", statement.Content())
+			log.Debugf("Subject summary = %s", subject.Summary())
+		}
+
+		hasObject := false
+		for _, category := range categories {
+			hasObject = hasObject || category.HasObject()
 		}
 
 		data := struct {
-			Statement statements.Statement
-			User      auth.User
+			SubjectKind string
+			SubjectText string
+			Categories  []assertions.AssertionType
+			HasObject   bool
+			HasSince    bool
+			Language    string
+			Formats     []assertions.Format
+			User        auth.User
 		}{
-			Statement: statement,
-			User:      user,
+			SubjectKind: subject.Type(),
+			SubjectText: subjectText(ctx, subject),
+			Categories:  categories,
+			HasObject:   hasObject,
+			HasSince:    slices.Contains(categories, assertions.IsCompromised),
+			Language:    requestLanguage(r),
+			Formats:     assertions.Formats(),
+			User:        user,
 		}
 
 		RenderWebPage(ctx, "addassertionform", data, nil, w, r)
 	} else if r.Method == "POST" {
-		log.InfofX(ctx, "Creating new assertion for statement")
+		log.InfofX(ctx, "Creating new assertion for %s", kind)
 		r.ParseForm()
 
 		keyId := r.Form.Get("sign_as")
@@ -472,21 +935,87 @@ func AddStatementAssertionWebHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		b64key, err := datastore.ActiveDataStore.FetchKey(keyUri)
+		category := assertions.AssertionTypeOf(r.Form.Get("assertion_type"))
+		if !slices.Contains(categories, category) {
+			HandleError(ctx, ErrorAssertionType.instance("Assertion type "+category.String()+" not valid for "+kind), w, r)
+			return
+		}
+
+		format := assertions.JwtFormat
+		if name := r.Form.Get("format"); name != "" {
+			if format, err = assertions.FormatOf(name); err != nil {
+				HandleError(ctx, ErrorAssertionFormat.instance(err.Error()), w, r)
+				return
+			}
+		}
+
+		privateKey, err := datastore.FetchPrivateKey(keyUri)
 		if err != nil {
 			HandleError(ctx, ErrorKeyFetch.instance("Error fetching entity private key"), w, r)
 			return
 		}
-		privateKey := entities.PrivateKeyFromString(b64key)
 
 		entity, _ := datastore.ActiveDataStore.FetchEntity(ctx, keyUri)
 
-		su := ref.MakeUri(statementHash, "statement")
-
 		confidence, _ := strconv.ParseFloat(r.Form.Get("confidence"), 32)
-		kind := r.Form.Get("assertion_type")
 
-		assertion := datastore.CreateAssertion(ctx, su, entity.Uri(), assertions.AssertionTypeOf(kind), confidence, privateKey)
+		claims := assertions.NewAssertion(category)
+		claims.Subject = subjectUri.String()
+		claims.Issuer = entity.Uri().String()
+		claims.Confidence = float32(confidence)
+
+		if category.HasObject() {
+			objectUri, appErr := assertionObject(ctx, r.Form.Get("object"), subjectUri, category)
+			if appErr != nil {
+				HandleError(ctx, *appErr, w, r)
+				return
+			}
+			claims.Object = objectUri.String()
+		}
+
+		if category == assertions.IsCompromised {
+			since, err := formTime(r, "since")
+			if err != nil || (since != nil && since.After(time.Now())) {
+				HandleError(ctx, ErrorCompromisedSince.instance("Compromise time not valid: "+r.Form.Get("since")), w, r)
+				return
+			}
+			claims.Since = since
+		}
+
+		validFrom, fromErr := formTime(r, "valid_from")
+		validUntil, untilErr := formTime(r, "valid_until")
+		if fromErr != nil || untilErr != nil {
+			HandleError(ctx, ErrorAssertionValidity.instance("Validity times not valid: "+r.Form.Get("valid_from")+" to "+r.Form.Get("valid_until")), w, r)
+			return
+		}
+		claims.NotBefore = validFrom
+		claims.ExpiresAt = validUntil
+
+		claims.Basis = strings.Fields(strings.ReplaceAll(r.Form.Get("basis"), ",", " "))
+
+		assertion, err := datastore.CreateSignedAssertionAs(ctx, claims, privateKey, format)
+		if goerrors.Is(err, datastore.ErrInvalidBasis) {
+			HandleError(ctx, ErrorAssertionBasis.instance(err.Error()), w, r)
+			return
+		} else if goerrors.Is(err, datastore.ErrInvalidValidity) {
+			HandleError(ctx, ErrorAssertionValidity.instance(err.Error()), w, r)
+			return
+		} else if goerrors.Is(err, datastore.ErrInvalidRetraction) {
+			HandleError(ctx, ErrorAssertionRetraction.instance(err.Error()), w, r)
+			return
+		} else if goerrors.Is(err, datastore.ErrInvalidCategory) {
+			HandleError(ctx, ErrorAssertionType.instance(err.Error()), w, r)
+			return
+		} else if goerrors.Is(err, assertions.ErrCompromised) {
+			HandleError(ctx, ErrorKeyCompromised.instance("Signing key for "+entity.Uri().String()+" declared compromised"), w, r)
+			return
+		} else if goerrors.Is(err, entities.ErrCertificateNotValid) {
+			HandleError(ctx, ErrorCertificateExpired.instance(err.Error()), w, r)
+			return
+		} else if err != nil {
+			HandleError(ctx, ErrorMakeAssertion.instance(err.Error()), w, r)
+			return
+		}
 
 		// Redirect the user to the assertion
 		http.Redirect(w, r, assertion.Uri().WebPath(), http.StatusSeeOther)
@@ -494,3 +1023,25 @@ func AddStatementAssertionWebHandler(w http.ResponseWriter, r *http.Request) {
 		log.DebugfX(ctx, "Redirecting to %s", assertion.Uri().WebPath())
 	}
 }
+
+// Validates the object of a new assertion, which must be an existing item of the kind that the category
+// requires, and must not be the subject itself.
+func assertionObject(ctx context.Context, object string, subjectUri ref.HashUri, category assertions.AssertionType) (ref.HashUri, *AppError) {
+	kind := category.Category().ObjectKind(subjectUri.Kind())
+
+	objectUri := ref.UriFromString(strings.TrimSpace(object))
+	if !objectUri.HasType() {
+		objectUri = objectUri.WithType(kind)
+	}
+
+	if objectUri.Kind() != kind || objectUri.Equals(subjectUri) {
+		err := ErrorAssertionObject.instance("Assertion object " + objectUri.String() + " not valid for " + subjectUri.String())
+		return objectUri, &err
+	}
+	if _, err := datastore.ActiveDataStore.Fetch(ctx, objectUri); err != nil {
+		appErr := ErrorAssertionObject.instance("Assertion object not found: " + objectUri.String())
+		return objectUri, &appErr
+	}
+
+	return objectUri, nil
+}
@@ -0,0 +1,315 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"silvatek.uk/trustedassertions/internal/appcontext"
+	"silvatek.uk/trustedassertions/internal/assertions"
+	"silvatek.uk/trustedassertions/internal/datastore"
+	ref "silvatek.uk/trustedassertions/internal/references"
+	"silvatek.uk/trustedassertions/internal/statements"
+	"silvatek.uk/trustedassertions/internal/trust"
+)
+
+// Builds the network of entities trusted from the roots of the logged-in user, which is built once for
+// each request and shared by everything on the page that depends on it.
+func trustNetworkFor(ctx context.Context, r *http.Request) *trust.Network {
+	roots := trust.RootsForUser(ctx, datastore.ActiveDataStore, authUsername(r))
+	return trust.NewNetwork(ctx, datastore.ActiveDataStore, roots)
+}
+
+// Returns the trust model named in the request's "model" parameter, or the server default model.
+func trustModelFor(ctx context.Context, r *http.Request) trust.TrustModel {
+	name := r.URL.Query().Get("model")
+	if name == "" {
+		name = trust.DefaultModel
+	}
+
+	model, err := trust.NewModel(name, datastore.ActiveDataStore)
+	if err != nil {
+		log.InfofX(ctx, "Using %s trust model: %v", trust.DefaultModel, err)
+		model, _ = trust.NewModel(trust.DefaultModel, datastore.ActiveDataStore)
+	}
+	return model
+}
+
+// Calculates the trust score for a statement using the active datastore.
+func trustScore(ctx context.Context, model trust.TrustModel, statementUri ref.HashUri, network *trust.Network) trust.Score {
+	score, err := model.ScoreIn(ctx, statementUri, network)
+	if err != nil {
+		log.ErrorfX(ctx, "Error calculating trust score for %s: %v", statementUri, err)
+	}
+	return score
+}
+
+// Returns the node for an entity in the network, or nil if the entity is not trusted.
+func entityTrust(network *trust.Network, entityUri ref.HashUri) *trust.Node {
+	node, found := network.Node(entityUri)
+	if !found {
+		return nil
+	}
+	return node
+}
+
+// Shows the tree of assertions and entities behind the trust score for a statement.
+func StatementTrustWebHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := appcontext.NewWebContext(r)
+
+	uri := ref.MakeUri(mux.Vars(r)["hash"], "statement")
+	statement, err := datastore.ActiveDataStore.FetchStatement(ctx, uri)
+	if err != nil {
+		HandleError(ctx, ErrorStatementFetch.instance("Error fetching statement "+uri.String()), w, r)
+		return
+	}
+
+	score := trustScore(ctx, trustModelFor(ctx, r), uri, trustNetworkFor(ctx, r))
+
+	data := struct {
+		Uri         ref.HashUri
+		Content     string
+		Score       trust.Score
+		Models      []string
+		Explanation *trust.Explanation
+	}{
+		Uri:         uri,
+		Content:     statement.Content(),
+		Score:       score,
+		Models:      trust.ModelNames(),
+		Explanation: trust.Explain(ctx, datastore.ActiveDataStore, score),
+	}
+
+	menu := []PageMenuItem{
+		{Text: "Statement", Target: uri.WebPath()},
+		{Text: "Raw", Target: uri.ApiPath() + "/trust?model=" + score.Model},
+	}
+
+	RenderWebPage(ctx, "viewtrust", data, menu, w, r)
+}
+
+// The latest version of an item that has been replaced, according to entities in the trust network.
+type supersession struct {
+	Latest   ref.HashUri
+	Summary  string
+	Versions int // The number of newer versions
+}
+
+// Returns the latest version of an item, or nil if it has not been replaced.
+func supersededBy(ctx context.Context, uri ref.HashUri, network *trust.Network) *supersession {
+	chain := trust.Supersessions(ctx, datastore.ActiveDataStore, uri, network)
+	if len(chain) == 0 {
+		return nil
+	}
+
+	latest := chain[len(chain)-1].Replacement
+	summary := latest.Short()
+	if item, err := datastore.ActiveDataStore.Fetch(ctx, latest); err == nil {
+		summary = item.Summary()
+	}
+
+	return &supersession{Latest: latest, Summary: summary, Versions: len(chain)}
+}
+
+// Returns the earliest compromise of an entity's key, asserted by the entity itself or by an
+// entity in the trust network, or nil if its key has not been compromised.
+func compromiseOf(ctx context.Context, entityUri ref.HashUri, network *trust.Network) *trust.Compromise {
+	compromise, found := trust.CompromiseOf(ctx, datastore.ActiveDataStore, entityUri, network)
+	if !found {
+		return nil
+	}
+	return &compromise
+}
+
+// Returns how the assertions about a statement disagree, whoever issued them.
+func statementContest(ctx context.Context, statementUri ref.HashUri) trust.Contest {
+	contest, err := trust.FindContest(ctx, datastore.ActiveDataStore, statementUri)
+	if err != nil {
+		log.ErrorfX(ctx, "Error finding contest for %s: %v", statementUri, err)
+	}
+	return contest
+}
+
+// A statement that trusted entities have asserted is the same as the one being viewed.
+type statementVariant struct {
+	Uri     ref.HashUri
+	Content string
+}
+
+// A reference to a statement, noting which equivalent statement it came from if not the one being viewed,
+// and whether an assertion making the reference is no longer (or not yet) in force or has been retracted.
+type clusterReference struct {
+	ref.Reference
+	Variant   *statementVariant
+	Inactive  string
+	Retracted bool
+}
+
+// Returns a description of why an assertion is not in force, or an empty string if it is in force or is not an assertion.
+func inactivity(ctx context.Context, uri ref.HashUri) string {
+	if !assertions.IsAssertionKind(uri.Kind()) {
+		return ""
+	}
+	assertion, err := datastore.ActiveDataStore.FetchAssertion(ctx, uri)
+	if err != nil || assertion.IsActive() {
+		return ""
+	}
+	return assertion.ValidityDescription()
+}
+
+// Whether the source of a reference is an assertion that has been retracted by its issuer.
+func isRetracted(ctx context.Context, uri ref.HashUri) bool {
+	if !assertions.IsAssertionKind(uri.Kind()) {
+		return false
+	}
+	assertion, err := datastore.ActiveDataStore.FetchAssertion(ctx, uri)
+	if err != nil {
+		return false
+	}
+	_, retracted := assertions.RetractionOf(ctx, datastore.ActiveDataStore, assertion)
+	return retracted
+}
+
+// Returns the statements that entities in the trust network have asserted are the same as a statement.
+func statementVariants(ctx context.Context, statementUri ref.HashUri, network *trust.Network) []statementVariant {
+	cluster := trust.Cluster(ctx, datastore.ActiveDataStore, statementUri, network)
+
+	variants := make([]statementVariant, 0, len(cluster)-1)
+	for _, uri := range cluster[1:] {
+		statement, err := datastore.ActiveDataStore.FetchStatement(ctx, uri)
+		if err != nil {
+			log.ErrorfX(ctx, "Error fetching equivalent statement %s: %v", uri, err)
+			continue
+		}
+		variants = append(variants, statementVariant{Uri: uri, Content: statement.Content()})
+	}
+	return variants
+}
+
+// Returns the references to a statement combined with those to its variants, each listed only once.
+func clusterReferences(ctx context.Context, statement *statements.Statement, variants []statementVariant) []clusterReference {
+	combined := make([]clusterReference, 0)
+	seen := make(map[string]bool)
+
+	add := func(target ref.Referenceable, variant *statementVariant) {
+		refs, err := datastore.ActiveDataStore.FetchRefs(ctx, target.Uri())
+		if err != nil {
+			log.ErrorfX(ctx, "Error fetching references to %s: %v", target.Uri(), err)
+			return
+		}
+		enrichReferencesTo(ctx, target, refs)
+		for _, reference := range refs {
+			if seen[reference.Source.Escaped()] {
+				continue
+			}
+			seen[reference.Source.Escaped()] = true
+			combined = append(combined, clusterReference{
+				Reference: reference,
+				Variant:   variant,
+				Inactive:  inactivity(ctx, reference.Source),
+				Retracted: isRetracted(ctx, reference.Source),
+			})
+		}
+	}
+
+	add(statement, nil)
+	for n := range variants {
+		variant, err := datastore.ActiveDataStore.FetchStatement(ctx, variants[n].Uri)
+		if err != nil {
+			continue
+		}
+		add(&variant, &variants[n])
+	}
+
+	return combined
+}
+
+// A contested statement as shown in the listing of contested statements.
+type contestView struct {
+	trust.Contest
+	Content string
+}
+
+// How long the listing of contested statements is kept before it is found again.
+var ContestedRefresh = 5 * time.Minute
+
+// The maximum number of contested statements that are listed.
+var MaxContested = 100
+
+// The listing of contested statements, which is found by checking every statement, so is shared between
+// requests and only found again once it is older than ContestedRefresh.
+type contestedListing struct {
+	mutex    sync.Mutex
+	store    datastore.DataStore
+	found    time.Time
+	contests []contestView
+}
+
+var contested contestedListing
+
+// Returns the contested statements in the active datastore, finding them again if the listing is out of date.
+func (l *contestedListing) fetch(ctx context.Context) ([]contestView, time.Time, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.store == datastore.ActiveDataStore && time.Since(l.found) < ContestedRefresh {
+		return l.contests, l.found, nil
+	}
+
+	uris, err := datastore.ActiveDataStore.FetchUris(ctx, "statement")
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error listing statements: %w", err)
+	}
+	contests, err := trust.FindContested(ctx, datastore.ActiveDataStore, uris)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error finding contested statements: %w", err)
+	}
+	if len(contests) > MaxContested {
+		contests = contests[:MaxContested]
+	}
+
+	views := make([]contestView, len(contests))
+	for n, contest := range contests {
+		statement, _ := datastore.ActiveDataStore.FetchStatement(ctx, contest.Statement)
+		views[n] = contestView{Contest: contest, Content: statement.Content()}
+	}
+
+	l.store = datastore.ActiveDataStore
+	l.found = time.Now()
+	l.contests = views
+	return l.contests, l.found, nil
+}
+
+// Discards the listing, so that it is found again when it is next fetched.
+func (l *contestedListing) clear() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.store = nil
+}
+
+// Lists the statements that have conflicting assertions, with the strongest disagreement first.
+//
+// Finding them means checking every statement, so the listing is only refreshed periodically and
+// is limited to the MaxContested strongest disagreements.
+func ContestedWebHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := appcontext.NewWebContext(r)
+
+	contests, found, err := contested.fetch(ctx)
+	if err != nil {
+		HandleError(ctx, ErrorStatementFetch.instance(err.Error()), w, r)
+		return
+	}
+
+	data := struct {
+		Contests []contestView
+		Found    time.Time
+	}{
+		Contests: contests,
+		Found:    found,
+	}
+
+	RenderWebPage(ctx, "contested", data, nil, w, r)
+}
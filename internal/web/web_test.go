@@ -5,11 +5,14 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"math/big"
+	"net/http/cookiejar"
 	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/mux"
 	"silvatek.uk/trustedassertions/internal/assertions"
 	"silvatek.uk/trustedassertions/internal/auth"
@@ -17,6 +20,7 @@ import (
 	"silvatek.uk/trustedassertions/internal/entities"
 	. "silvatek.uk/trustedassertions/internal/references"
 	"silvatek.uk/trustedassertions/internal/testdata"
+	"silvatek.uk/trustedassertions/internal/trust"
 	"silvatek.uk/trustedassertions/internal/webtest"
 )
 
@@ -34,6 +38,7 @@ func NewWebTest(t *testing.T) *webtest.WebTest {
 	datastore.ActiveDataStore.Store(context.Background(), &signer)
 	datastore.ActiveDataStore.StoreKey(signer.Uri(), entities.PrivateKeyToString(privateKey))
 	DefaultEntityUri = signer.Uri()
+	trust.DefaultRoots = trust.NewRoots(signer.Uri())
 
 	testdata.SetupTestData(context.Background(), "../../testdata", signer.Uri().String(), entities.PrivateKeyToString(privateKey))
 
@@ -93,6 +98,22 @@ func TestAssertionPage(t *testing.T) {
 
 	page := wt.GetPage("/web/assertions/514518bb09d57524bc6b96842721e4c4404cb4a3329aadf1761bb3eddb2832da")
 	page.AssertHtmlQuery("#category", "IsTrue")
+
+	// Subjects without a type are linked as whatever kind of item is stored
+	ctx := context.TODO()
+	trusted := datastore.CreateEntityWithKey(ctx, "Untyped subject")
+	claims := assertions.NewAssertion(assertions.IsTrusted)
+	claims.Subject = trusted.Unadorned()
+	claims.Issuer = DefaultEntityUri.String()
+	privateKey, _ := datastore.FetchPrivateKey(DefaultEntityUri)
+	assertion, err := datastore.CreateSignedAssertion(ctx, claims, privateKey)
+	if err != nil {
+		t.Fatalf("Error creating assertion: %v", err)
+	}
+	page = wt.GetPage(assertion.Uri().WebPath())
+	if page.Find("a[href='"+trusted.WebPath()+"']") == "" {
+		t.Errorf("Untyped subject should link to its entity page")
+	}
 }
 
 func TestNewStatementPage(t *testing.T) {
@@ -195,3 +216,716 @@ func TestQrCode(t *testing.T) {
 	page.AssertHtmlQuery("h2", "Share Item")
 	page.AssertSuccessResponse()
 }
+
+func TestStatementTrustScore(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	page := wt.GetPage("/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f")
+	page.AssertHtmlQuery("#trustscore", "No assertions from trusted entities")
+
+	values := url.Values{
+		"assertion_type": {"IsTrue"},
+		"confidence":     {"0.8"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	wt.PostFormData("/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f/addassertion", values)
+
+	page = wt.GetPage("/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f")
+	page.AssertHtmlQuery("#trustscore", "90% likely to be true")
+}
+
+func TestAddEntityAssertion(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	entityPath := "/web/entities/177ed36580cf1ed395e1d0d3a7709993ac1599ee844dc4cf5b9573a1265df2db"
+
+	page := wt.GetPage(entityPath)
+	page.AssertHtmlQuery("#trust", "Not trusted")
+	page.AssertHtmlQuery("a", "Add a new assertion for this entity.")
+
+	page = wt.GetPage(entityPath + "/addassertion")
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("option", "Entity is trustworthy")
+
+	values := url.Values{
+		"assertion_type": {"IsTrusted"},
+		"confidence":     {"0.8"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	page = wt.PostFormData(entityPath+"/addassertion", values)
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#category", "IsTrusted")
+
+	page = wt.GetPage(entityPath)
+	page.AssertHtmlQuery("#trust", "0.40")
+
+	// Mr Tester is now trusted, so their assertion about the universe counts
+	page = wt.GetPage("/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f")
+	page.AssertHtmlQuery("#trustscore", "from 1 trusted assertion")
+}
+
+func TestAddAssertionWrongType(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	values := url.Values{
+		"assertion_type": {"IsTrue"},
+		"confidence":     {"0.8"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	page := wt.PostFormData("/web/entities/177ed36580cf1ed395e1d0d3a7709993ac1599ee844dc4cf5b9573a1265df2db/addassertion", values)
+	page.AssertHtmlQuery("#message", "Assertion type not valid for subject")
+}
+
+func TestStatementTrustPage(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	statementPath := "/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f"
+
+	page := wt.GetPage(statementPath + "/trust")
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#explanation", "No assertions from trusted entities")
+
+	values := url.Values{
+		"assertion_type": {"IsTrusted"},
+		"confidence":     {"1.0"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	wt.PostFormData("/web/entities/177ed36580cf1ed395e1d0d3a7709993ac1599ee844dc4cf5b9573a1265df2db/addassertion", values)
+
+	page = wt.GetPage(statementPath + "/trust")
+	page.AssertHtmlQuery("#score", "50%")
+	page.AssertHtmlQuery("#explanation", "Mr Tester")
+	if page.Find("#explanation a[href='/web/entities/177ed36580cf1ed395e1d0d3a7709993ac1599ee844dc4cf5b9573a1265df2db']") == "" {
+		t.Error("Explanation does not link to the issuing entity")
+	}
+	page.AssertHtmlQuery("#explanation", "Signing entity")
+	page.AssertHtmlQuery("#explanation .trustedge", "category=IsTrusted confidence=1.00 weight=1.00")
+	page.AssertHtmlQuery("#explanation .trustedge", "trusted root")
+}
+
+func TestProfileTrustRoots(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	testerUri := "hash://sha256/177ed36580cf1ed395e1d0d3a7709993ac1599ee844dc4cf5b9573a1265df2db?type=entity"
+	statementPath := "/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f"
+
+	page := wt.GetPage("/web/profile")
+	page.AssertHtmlQuery("#trustroots", "server defaults")
+
+	page = wt.PostFormData("/web/profile", url.Values{"action": {"addroot"}, "entity": {testerUri}, "weight": {"0.5"}})
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#trustroots", "Mr Tester")
+	page.AssertHtmlQuery("#trustroots", "weight 0.50")
+
+	stored, _ := datastore.ActiveDataStore.FetchUser(context.TODO(), user.Id)
+	if len(stored.TrustRoots) != 1 || stored.TrustRoots[0].Entity != testerUri {
+		t.Errorf("Trust roots not stored: %v", stored.TrustRoots)
+	}
+
+	// The logged-in user now trusts Mr Tester directly
+	page = wt.GetPage(statementPath)
+	page.AssertHtmlQuery("#trustscore", "from 1 trusted assertion")
+	page = wt.GetPage("/web/assertions/514518bb09d57524bc6b96842721e4c4404cb4a3329aadf1761bb3eddb2832da")
+	page.AssertHtmlQuery("#issuertrust", "0.50")
+
+	// Anonymous visitors still use the server default roots
+	authCookie, authJar := wt.AuthCookie, wt.Client.Jar
+	wt.AuthCookie = nil
+	wt.Client.Jar, _ = cookiejar.New(nil)
+	page = wt.GetPage(statementPath)
+	page.AssertHtmlQuery("#trustscore", "No assertions from trusted entities")
+	wt.AuthCookie, wt.Client.Jar = authCookie, authJar
+
+	page = wt.PostFormData("/web/profile", url.Values{"action": {"removeroot"}, "entity": {testerUri}})
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#trustroots", "server defaults")
+}
+
+func TestProfileTrustRootNotFound(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	values := url.Values{"action": {"addroot"}, "entity": {"0123456789abcdef"}, "weight": {"1.0"}}
+	page := wt.PostFormData("/web/profile", values)
+	page.AssertHtmlQuery("#message", "Trusted entity not valid")
+}
+
+func TestContestedStatements(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	statementPath := "/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f"
+
+	page := wt.GetPage("/web/contested")
+	page.AssertHtmlQuery("#nocontests", "No contested statements found.")
+
+	values := url.Values{
+		"assertion_type": {"IsFalse"},
+		"confidence":     {"0.7"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	wt.PostFormData(statementPath+"/addassertion", values)
+
+	page = wt.GetPage(statementPath)
+	page.AssertHtmlQuery("#contested", "Contested")
+
+	// The listing is only found again once it is out of date
+	page = wt.GetPage("/web/contested")
+	page.AssertHtmlQuery("#nocontests", "No contested statements found.")
+
+	contested.clear()
+	page = wt.GetPage("/web/contested")
+	page.AssertHtmlQuery("#contests", "The universe exists")
+}
+
+func TestStatementTrustPageModels(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	trustPath := "/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f/trust"
+
+	page := wt.GetPage(trustPath)
+	page.AssertHtmlQuery("#model", "weighted")
+	page.AssertHtmlQuery("#models a", "beta")
+
+	page = wt.GetPage(trustPath + "?model=beta")
+	page.AssertHtmlQuery("#model", "beta")
+	page.AssertHtmlQuery("#interval", "credible interval")
+
+	page = wt.GetPage(trustPath + "?model=astrology")
+	page.AssertHtmlQuery("#model", "weighted")
+}
+
+func TestReplacesAssertion(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	olderPath := "/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f"
+	newer := datastore.CreateStatement(context.TODO(), "The universe definitely exists")
+
+	page := wt.GetPage(newer.WebPath() + "/addassertion")
+	page.AssertHtmlQuery("option", "Statement replaces")
+	page.AssertHtmlQuery("label[for=object]", "Replaces")
+
+	values := url.Values{
+		"assertion_type": {"Replaces"},
+		"object":         {"e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f"},
+		"confidence":     {"1.0"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	page = wt.PostFormData(newer.WebPath()+"/addassertion", values)
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#category", "Replaces")
+
+	page = wt.GetPage(olderPath)
+	page.AssertHtmlQuery("#superseded", "Superseded by The universe definitely exists")
+	if page.Find("#superseded a[href='"+newer.WebPath()+"']") == "" {
+		t.Error("Superseded banner does not link to the newer statement")
+	}
+
+	page = wt.GetPage(newer.WebPath())
+	if page.Find("#superseded") != "" {
+		t.Error("Latest version should not be marked as superseded")
+	}
+}
+
+func TestSameAsAssertion(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	originalPath := "/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f"
+	variant := datastore.CreateStatement(context.TODO(), "The cosmos exists")
+
+	values := url.Values{
+		"assertion_type": {"IsSameAs"},
+		"object":         {"e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f"},
+		"confidence":     {"1.0"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	page := wt.PostFormData(variant.WebPath()+"/addassertion", values)
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#category", "IsSameAs")
+
+	page = wt.GetPage(variant.WebPath())
+	page.AssertHtmlQuery("#variants", "The universe exists")
+	page.AssertHtmlQuery("#references .variant", `via "The universe exists"`)
+
+	page = wt.GetPage(originalPath)
+	page.AssertHtmlQuery("#variants", "The cosmos exists")
+	if page.Find("#references .variant") != "" {
+		t.Error("References to the viewed statement should not be labelled as coming from a variant")
+	}
+}
+
+func TestCompromisedAssertion(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	entityPath := DefaultEntityUri.WebPath()
+
+	page := wt.GetPage(entityPath + "/addassertion")
+	page.AssertHtmlQuery("option", "Entity is compromised")
+
+	values := url.Values{
+		"assertion_type": {"IsCompromised"},
+		"since":          {"not a time"},
+		"confidence":     {"1.0"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	page = wt.PostFormData(entityPath+"/addassertion", values)
+	page.AssertHtmlQuery("#message", "Compromise time not valid")
+
+	values.Set("since", time.Now().UTC().Add(-time.Hour).Format("2006-01-02T15:04"))
+	page = wt.PostFormData(entityPath+"/addassertion", values)
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#category", "IsCompromised")
+
+	page = wt.GetPage(entityPath)
+	page.AssertHtmlQuery("#compromised", "Signing key compromised since")
+
+	values = url.Values{
+		"assertion_type": {"IsTrue"},
+		"confidence":     {"1.0"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	page = wt.PostFormData("/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f/addassertion", values)
+	page.AssertHtmlQuery("#message", "Signing key has been declared compromised")
+
+	// Whoever holds the leaked key can still sign assertions without the server
+	privateKey, _ := datastore.FetchPrivateKey(DefaultEntityUri)
+	statement := datastore.CreateStatement(context.TODO(), "Signed with a leaked key")
+	forged := assertions.NewAssertion(assertions.IsTrue)
+	forged.Subject = statement.String()
+	forged.Issuer = DefaultEntityUri.String()
+	forged.IssuedAt = jwt.NewNumericDate(time.Now())
+	forged.MakeJwt(privateKey)
+	datastore.ActiveDataStore.Store(context.TODO(), &forged)
+	page = wt.GetPage(forged.Uri().WebPath())
+	page.AssertHtmlQuery("#message", "Assertion was issued after its signing key was compromised")
+}
+
+func TestAssertionBasis(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	statement := datastore.CreateStatement(context.TODO(), "Something exists")
+
+	values := url.Values{
+		"assertion_type": {"IsTrue"},
+		"basis":          {"514518bb09d57524bc6b96842721e4c4404cb4a3329aadf1761bb3eddb2832da"},
+		"confidence":     {"0.8"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	page := wt.PostFormData(statement.WebPath()+"/addassertion", values)
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#basis", "claims that 'The universe exists' is true")
+
+	values.Set("basis", "e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f?type=statement")
+	page = wt.PostFormData(statement.WebPath()+"/addassertion", values)
+	page.AssertHtmlQuery("#message", "Assertion basis not valid")
+}
+
+func TestEndorseAssertion(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	originalPath := "/web/assertions/514518bb09d57524bc6b96842721e4c4404cb4a3329aadf1761bb3eddb2832da"
+
+	page := wt.GetPage(originalPath)
+	if page.Find("#endorse[href='./514518bb09d57524bc6b96842721e4c4404cb4a3329aadf1761bb3eddb2832da/addassertion']") == "" {
+		t.Error("Assertion page does not link to endorse or dispute the assertion")
+	}
+
+	page = wt.GetPage(originalPath + "/addassertion")
+	page.AssertHtmlQuery("option", "Assertion is endorsed")
+	page.AssertHtmlQuery("option", "Assertion is disputed")
+	page.AssertHtmlQuery("#content", "claims that 'The universe exists' is true")
+
+	values := url.Values{
+		"assertion_type": {"IsDisputed"},
+		"confidence":     {"0.7"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	page = wt.PostFormData(originalPath+"/addassertion", values)
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#category", "IsDisputed")
+	page.AssertHtmlQuery("#subjecttext", "claims that 'The universe exists' is true")
+
+	page = wt.GetPage(originalPath)
+	page.AssertHtmlQuery("ul", "claims that 'The universe exists' is true' is disputed")
+}
+
+func TestAssertionValidityWindow(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	statement := datastore.CreateStatement(context.TODO(), "Valid next week")
+	statementPath := statement.WebPath()
+
+	values := url.Values{
+		"assertion_type": {"IsTrue"},
+		"valid_until":    {time.Now().UTC().Add(-time.Hour).Format("2006-01-02T15:04")},
+		"confidence":     {"0.8"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	page := wt.PostFormData(statementPath+"/addassertion", values)
+	page.AssertHtmlQuery("#message", "Assertion validity period not valid")
+
+	values.Set("valid_from", time.Now().UTC().Add(7*24*time.Hour).Format("2006-01-02T15:04"))
+	values.Set("valid_until", time.Now().UTC().Add(14*24*time.Hour).Format("2006-01-02T15:04"))
+	page = wt.PostFormData(statementPath+"/addassertion", values)
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#validity .badge", "Not valid until")
+
+	page = wt.GetPage(statementPath)
+	page.AssertHtmlQuery("#references .badge", "Not valid until")
+	page.AssertHtmlQuery("#trustscore", "No assertions from trusted entities")
+}
+
+func TestReplacesAssertionBadObject(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	values := url.Values{
+		"assertion_type": {"Replaces"},
+		"object":         {"177ed36580cf1ed395e1d0d3a7709993ac1599ee844dc4cf5b9573a1265df2db?type=entity"},
+		"confidence":     {"1.0"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	page := wt.PostFormData("/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f/addassertion", values)
+	page.AssertHtmlQuery("#message", "Assertion object not valid")
+}
+
+func TestRequestLanguage(t *testing.T) {
+	r := httptest.NewRequest("GET", "/web/statements/1234/addassertion", nil)
+	if requestLanguage(r) != assertions.DefaultLanguage {
+		t.Errorf("Unexpected default language: %s", requestLanguage(r))
+	}
+
+	r.Header.Set("Accept-Language", "fr-CA;q=0.9, en;q=0.8")
+	if requestLanguage(r) != "fr-CA" {
+		t.Errorf("Unexpected preferred language: %s", requestLanguage(r))
+	}
+}
+
+func TestRetractAssertion(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	ctx := context.TODO()
+	original, _ := datastore.CreateStatementAndAssertion(ctx, "I was wrong about this", DefaultEntityUri, assertions.IsTrue, 0.9)
+	originalPath := original.Uri().WebPath()
+
+	page := wt.GetPage(originalPath + "/addassertion")
+	page.AssertHtmlQuery("option", "Assertion is retracted")
+
+	values := url.Values{
+		"assertion_type": {"Retracts"},
+		"confidence":     {"1.0"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	page = wt.PostFormData(originalPath+"/addassertion", values)
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#category", "Retracts")
+
+	page = wt.GetPage(originalPath)
+	page.AssertHtmlQuery("#retracted", "Withdrawn by its issuer")
+
+	page = wt.GetPage(UriFromString(original.Subject).WebPath())
+	page.AssertHtmlQuery("#references li.retracted", "I was wrong about this")
+
+	otherUri := datastore.CreateEntityWithKey(ctx, "Someone else")
+	other, _ := datastore.CreateStatementAndAssertion(ctx, "Not mine to retract", otherUri, assertions.IsTrue, 0.9)
+	page = wt.PostFormData(other.Uri().WebPath()+"/addassertion", values)
+	page.AssertHtmlQuery("#message", "Only the issuer of an assertion can retract it")
+}
+
+func TestAssertionForOtherAudience(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	b64key, _ := datastore.ActiveDataStore.FetchKey(DefaultEntityUri)
+	assertion := assertions.NewAssertion(assertions.IsTrue)
+	assertion.Audience = []string{"trustedassertions:9.9/any"}
+	assertion.Subject = "hash://sha256/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f?type=statement"
+	assertion.Issuer = DefaultEntityUri.String()
+	assertion.MakeJwt(entities.PrivateKeyFromString(b64key))
+	datastore.ActiveDataStore.Store(context.TODO(), &assertion)
+
+	page := wt.GetPage(assertion.Uri().WebPath())
+	page.AssertHtmlQuery("#message", "Assertion is not for a supported protocol version")
+}
+
+func TestNewEntityKeyAlgorithm(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	page := wt.GetPage("/web/newentity")
+	page.AssertHtmlQuery("#algorithm option[selected]", "RSA 2048 (RS256)")
+	page.AssertHtmlQuery("#algorithm", "Ed25519 (EdDSA)")
+
+	page = wt.PostFormData("/web/newentity", url.Values{"commonname": {"Ed25519 entity"}, "algorithm": {"Ed25519"}})
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#algorithm", "Ed25519 (EdDSA)")
+	uri := UriFromString(page.Find("span.fulluri"))
+
+	b64key, err := datastore.ActiveDataStore.FetchKey(uri)
+	if err != nil || entities.AlgorithmOf(entities.PrivateKeyFromString(b64key)) != entities.Ed25519 {
+		t.Errorf("Ed25519 key not stored for new entity: %v", err)
+	}
+
+	statement := datastore.CreateStatement(context.TODO(), "Signed with Ed25519")
+	assertion, err := datastore.CreateAssertion(context.TODO(), statement, uri, assertions.IsTrue, 0.8, entities.PrivateKeyFromString(b64key))
+	if err != nil {
+		t.Fatalf("Error creating assertion: %v", err)
+	}
+	page = wt.GetPage(assertion.Uri().WebPath())
+	page.AssertHtmlQuery("#issuername", "Ed25519 entity")
+
+	page = wt.PostFormData("/web/newentity", url.Values{"commonname": {"DSA entity"}, "algorithm": {"DSA"}})
+	page.AssertHtmlQuery("#message", "Key algorithm not supported")
+}
+
+func TestNewMemberEntity(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	page := wt.GetPage("/web/newentity")
+	page.AssertHtmlQuery("#parent option", "None")
+
+	values := url.Values{"commonname": {"Test department"}, "algorithm": {"P-256"}, "parent": {user.KeyRefs[0].KeyId}}
+	page = wt.PostFormData("/web/newentity", values)
+	page.AssertSuccessResponse()
+	if page.Find("#parent a[href='"+DefaultEntityUri.WebPath()+"']") == "" {
+		t.Error("Member page does not link to its organisation")
+	}
+	if page.Find("#parent .badge") != "" {
+		t.Error("Member certificate chain should be valid")
+	}
+	member := UriFromString(page.Find("span.fulluri"))
+
+	page = wt.GetPage(DefaultEntityUri.WebPath())
+	page.AssertHtmlQuery("#members", "Test department")
+	if page.Find("#members a[href='"+member.WebPath()+"']") == "" {
+		t.Error("Organisation page does not link to its member")
+	}
+
+	otherUri := datastore.CreateEntityWithKey(context.TODO(), "Not my organisation")
+	values.Set("parent", otherUri.Escaped())
+	page = wt.PostFormData("/web/newentity", values)
+	page.AssertHtmlQuery("#message", "Error accessing key")
+}
+
+func TestRenewEntity(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+	ctx := context.TODO()
+
+	privateKey, _ := entities.GenerateKey(entities.ECDSA)
+	expiring := entities.Entity{CommonName: "Expiring entity", Issued: time.Now().Add(-entities.CertificateLifetime + 24*time.Hour)}
+	expiring.MakeCertificate(privateKey)
+	datastore.ActiveDataStore.Store(ctx, &expiring)
+	datastore.ActiveDataStore.StoreKey(expiring.Uri(), entities.PrivateKeyToString(privateKey))
+
+	page := wt.GetPage(DefaultEntityUri.WebPath())
+	if page.Find("#expiry") != "" {
+		t.Error("New entity should not have an expiry warning")
+	}
+
+	page = wt.GetPage(expiring.Uri().WebPath())
+	page.AssertHtmlQuery("#expiry", "Certificate expires on")
+
+	// Only a user holding the entity's key can renew it
+	page = wt.PostFormData(expiring.Uri().WebPath()+"/renew", url.Values{})
+	page.AssertHtmlQuery("#message", "Error accessing key")
+
+	user.AddKeyRef(expiring.Uri().Escaped(), expiring.CommonName)
+	datastore.ActiveDataStore.StoreUser(ctx, *user)
+
+	page = wt.PostFormData(expiring.Uri().WebPath()+"/renew", url.Values{})
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#common_name", "Expiring entity")
+	if page.Find("#expiry") != "" {
+		t.Error("Renewed entity should not have an expiry warning")
+	}
+	if page.Find("#predecessor a[href='"+expiring.Uri().WebPath()+"']") == "" {
+		t.Error("Renewed entity does not link to the original")
+	}
+	renewed := UriFromString(page.Find("span.fulluri"))
+
+	page = wt.GetPage(expiring.Uri().WebPath())
+	if page.Find("#successor a[href='"+renewed.WebPath()+"']") == "" {
+		t.Error("Original entity does not link to its renewal")
+	}
+	if page.Find("#renew") != "" {
+		t.Error("Renewed entity should not offer renewal again")
+	}
+
+	stored, _ := datastore.ActiveDataStore.FetchUser(ctx, user.Id)
+	if !stored.HasKey(renewed.Escaped()) {
+		t.Error("User should hold the key of the renewed entity")
+	}
+}
+
+func TestRotateEntityKey(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	page := wt.GetPage(DefaultEntityUri.WebPath())
+	page.AssertHtmlQuery("#rotate_algorithm option", "Ed25519 (EdDSA)")
+
+	page = wt.PostFormData(DefaultEntityUri.WebPath()+"/rotate", url.Values{"algorithm": {"Ed25519"}})
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#algorithm", "Ed25519 (EdDSA)")
+	if page.Find("#predecessor a[href='"+DefaultEntityUri.WebPath()+"']") == "" {
+		t.Error("Rotated entity does not link to the original")
+	}
+	page.AssertHtmlQuery("#trust", "1.00")
+	rotated := UriFromString(page.Find("span.fulluri"))
+
+	page = wt.GetPage(DefaultEntityUri.WebPath())
+	if page.Find("#successor a[href='"+rotated.WebPath()+"']") == "" {
+		t.Error("Original entity does not link to its rotated key")
+	}
+	if page.Find("#rotate") != "" {
+		t.Error("Original entity should not offer rotation again")
+	}
+
+	stored, _ := datastore.ActiveDataStore.FetchUser(context.TODO(), user.Id)
+	if !stored.HasKey(rotated.Escaped()) || stored.HasKey(user.KeyRefs[0].KeyId) {
+		t.Errorf("User's key reference should be replaced with the rotated key: %v", stored.KeyRefs)
+	}
+
+	// The old key can no longer be rotated by the user
+	page = wt.PostFormData(DefaultEntityUri.WebPath()+"/rotate", url.Values{})
+	page.AssertHtmlQuery("#message", "Error accessing key")
+}
+
+func TestReissueMemberEntity(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+	ctx := context.TODO()
+
+	organisation := datastore.CreateEntityWithKey(ctx, "Someone else's organisation")
+	member, err := datastore.CreateMemberEntity(ctx, "Member", entities.ECDSA, organisation)
+	if err != nil {
+		t.Fatalf("Error creating member entity: %v", err)
+	}
+	user.AddKeyRef(member.Escaped(), "Member")
+	datastore.ActiveDataStore.StoreUser(ctx, *user)
+
+	// The member's certificate is issued with the organisation's key, which the user does not hold
+	page := wt.GetPage(member.WebPath())
+	if page.Find("#rotate") != "" {
+		t.Error("Member entity should not offer rotation without the organisation's key")
+	}
+	page = wt.PostFormData(member.WebPath()+"/renew", url.Values{})
+	page.AssertHtmlQuery("#message", "Error accessing key")
+	page = wt.PostFormData(member.WebPath()+"/rotate", url.Values{})
+	page.AssertHtmlQuery("#message", "Error accessing key")
+
+	user.AddKeyRef(organisation.Escaped(), "Someone else's organisation")
+	datastore.ActiveDataStore.StoreUser(ctx, *user)
+
+	page = wt.PostFormData(member.WebPath()+"/rotate", url.Values{})
+	page.AssertSuccessResponse()
+	if page.Find("#predecessor a[href='"+member.WebPath()+"']") == "" {
+		t.Error("Rotated member does not link to the original")
+	}
+}
+
+func TestSubmitSignedAssertion(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	// The entity's certificate is stored, but its private key is kept elsewhere
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	offline := entities.NewEntity("Offline signer", *big.NewInt(654321))
+	offline.MakeCertificate(privateKey)
+	datastore.ActiveDataStore.Store(context.TODO(), &offline)
+	statement := datastore.CreateStatement(context.TODO(), "Signed without the server")
+
+	claims := assertions.NewAssertion(assertions.IsTrue)
+	claims.Subject = statement.String()
+	claims.Issuer = offline.Uri().String()
+	claims.IssuedAt = jwt.NewNumericDate(time.Now())
+	claims.MakeJwt(privateKey)
+
+	page := wt.GetPage("/web/submitassertion")
+	page.AssertSuccessResponse()
+
+	page = wt.PostFormData("/web/submitassertion", url.Values{"jwt": {claims.Content()}})
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("span.fulluri", claims.Uri().String())
+	page.AssertHtmlQuery("#issuername", "Offline signer")
+	page.AssertHtmlQuery("#subjecttext", "Signed without the server")
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	claims.MakeJwt(otherKey)
+	page = wt.PostFormData("/web/submitassertion", url.Values{"jwt": {claims.Content()}})
+	page.AssertHtmlQuery("#message", "Signed assertion not valid")
+}
+
+func TestTimestampedAssertion(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+	defer assertions.UseTimestampAuthority(DefaultEntityUri)()
+
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	offline := entities.Entity{CommonName: "Offline signer", Issued: time.Now().AddDate(0, -1, 0)}
+	offline.MakeCertificate(privateKey)
+	datastore.ActiveDataStore.Store(context.TODO(), &offline)
+	statement := datastore.CreateStatement(context.TODO(), "Signed a while ago")
+
+	claims := assertions.NewAssertion(assertions.IsTrue)
+	claims.Subject = statement.String()
+	claims.Issuer = offline.Uri().String()
+	claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-48 * time.Hour))
+	claims.MakeJwt(privateKey)
+
+	page := wt.PostFormData("/web/submitassertion", url.Values{"jwt": {claims.Content()}})
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#timestamp", "Received "+time.Now().Format("2 Jan 2006"))
+	page.AssertHtmlQuery("#backdated", "Claims to have been issued on "+claims.IssuedAt.Format("2 Jan 2006 15:04"))
+	page.AssertHtmlQuery("#timestamp a", "timestamp")
+}
+
+func TestAddCoseAssertion(t *testing.T) {
+	wt := NewWebTest(t)
+	defer wt.Close()
+
+	statementPath := "/web/statements/e88688ef18e5c82bb8ea474eceeac8c6eb81d20ec8d903750753d3137865d10f"
+	page := wt.GetPage(statementPath + "/addassertion")
+	page.AssertHtmlQuery("#format option", "COSE")
+
+	values := url.Values{
+		"assertion_type": {"IsTrue"},
+		"confidence":     {"0.75"},
+		"format":         {"COSE"},
+		"sign_as":        {user.KeyRefs[0].KeyId},
+	}
+	page = wt.PostFormData(statementPath+"/addassertion", values)
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#format", "COSE")
+
+	uri := UriFromString(page.Find("span.fulluri"))
+	if uri.Kind() != assertions.COSE_KIND {
+		t.Errorf("Unexpected kind of COSE assertion: %s", uri)
+	}
+
+	// COSE assertions can themselves be endorsed
+	page = wt.GetPage(uri.WebPath() + "/addassertion")
+	page.AssertHtmlQuery("#assertion_type option", "CoseAssertion is endorsed")
+	page = wt.PostFormData(uri.WebPath()+"/addassertion", url.Values{"assertion_type": {"IsEndorsed"}, "confidence": {"0.9"}, "sign_as": {user.KeyRefs[0].KeyId}})
+	page.AssertSuccessResponse()
+	page.AssertHtmlQuery("#format", "JWT")
+
+	values.Set("format", "XML")
+	page = wt.PostFormData(statementPath+"/addassertion", values)
+	page.AssertHtmlQuery("#message", "Assertion format not supported")
+}